@@ -4,8 +4,10 @@ Copyright © 2026 JACOB ARTHURS
 package cmd
 
 import (
-	"github.com/jacobarthurs/shipbin/internal/config"
-	"github.com/jacobarthurs/shipbin/internal/npm"
+	"fmt"
+	"os"
+
+	"github.com/jacobarthurs/shipbin/pkg/shipbin"
 	"github.com/spf13/cobra"
 )
 
@@ -13,6 +15,9 @@ var (
 	flagOrg        string
 	flagTag        string
 	flagProvenance bool
+	flagNpmCLI     bool
+	flagRegistry   string
+	flagAccess     string
 )
 
 var npmCmd = &cobra.Command{
@@ -23,49 +28,58 @@ var npmCmd = &cobra.Command{
 Builds a platform-specific package for each artifact (e.g. @org/name-linux-x64)
 containing the binary, then publishes a root package (e.g. name) that declares
 them as optional dependencies. Users install the root package and npm resolves
-the correct platform package automatically.`,
+the correct platform package automatically.
+
+Packages are uploaded to the registry directly, authenticated with
+NODE_AUTH_TOKEN or an _authToken from .npmrc. --provenance and --npm-cli
+publish with the npm CLI instead. Packages go to --registry when set, otherwise to the
+registry .npmrc configures for their scope, otherwise to registry.npmjs.org.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := buildNpmConfig()
-		if err != nil {
-			return err
-		}
-		return npm.Publish(cfg)
+		return runTargets(cmd, []string{"npm"})
 	},
 }
 
-func buildNpmConfig() (*npm.Config, error) {
-	version, err := config.ResolveVersion(flagVersion)
-	if err != nil {
-		return nil, err
+func buildNpmConfig(cmd *cobra.Command) (shipbin.NpmConfig, error) {
+	if f := projectFile; f != nil {
+		applyFileValue(cmd, "org", &flagOrg, f.Npm.Org)
+		applyFileValue(cmd, "tag", &flagTag, f.Npm.Tag)
+		applyFileValue(cmd, "registry", &flagRegistry, f.Npm.Registry)
+		applyFileValue(cmd, "access", &flagAccess, f.Npm.Access)
+		if f.Npm.Provenance != nil && !cmd.Flags().Changed("provenance") {
+			flagProvenance = *f.Npm.Provenance
+		}
+		if f.Npm.CLI && !cmd.Flags().Changed("npm-cli") {
+			flagNpmCLI = true
+		}
 	}
-
-	artifacts, err := config.ParseArtifacts(flagArtifacts)
-	if err != nil {
-		return nil, err
+	if !cmd.Flags().Changed("provenance") && (projectFile == nil || projectFile.Npm.Provenance == nil) {
+		// GitHub Actions jobs with id-token: write can publish with provenance.
+		flagProvenance = os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL") != ""
+	}
+	if flagOrg == "" {
+		return shipbin.NpmConfig{}, fmt.Errorf(`required flag(s) "org" not set`)
 	}
 
-	cfg := &npm.Config{
-		Name:       flagName,
-		Version:    version,
-		Summary:    flagSummary,
-		License:    flagLicense,
-		Artifacts:  artifacts,
-		DryRun:     flagDryRun,
+	return shipbin.NpmConfig{
 		Org:        flagOrg,
 		Tag:        flagTag,
 		Provenance: flagProvenance,
-		Readme:     flagReadme,
-	}
-
-	return cfg, nil
+		UseCLI:     flagNpmCLI,
+		Registry:   flagRegistry,
+		Access:     flagAccess,
+	}, nil
 }
 
 func init() {
-	npmCmd.Flags().StringVar(&flagOrg, "org", "", "npm org scope (e.g. 'myorg' produces @myorg/name-linux-x64)")
-	npmCmd.Flags().StringVar(&flagTag, "tag", "latest", "dist-tag to publish under (e.g. latest, next, beta)")
-	npmCmd.Flags().BoolVar(&flagProvenance, "provenance", true, "publish with provenance attestation (requires CI environment)")
+	addNpmFlags(npmCmd)
+	addPublishFlags(npmCmd)
+}
 
-	if err := npmCmd.MarkFlagRequired("org"); err != nil {
-		panic(err)
-	}
+func addNpmFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&flagOrg, "org", "", "npm org scope (e.g. 'myorg' produces @myorg/name-linux-x64)")
+	cmd.Flags().StringVar(&flagTag, "tag", "latest", "dist-tag to publish under (e.g. latest, next, beta)")
+	cmd.Flags().BoolVar(&flagProvenance, "provenance", false, "publish with provenance attestation through the npm CLI (default: true in GitHub Actions jobs with id-token: write)")
+	cmd.Flags().StringVar(&flagRegistry, "registry", "", "registry URL to publish to (default: from .npmrc, else https://registry.npmjs.org/)")
+	cmd.Flags().StringVar(&flagAccess, "access", "public", "package access level: public or restricted")
+	cmd.Flags().BoolVar(&flagNpmCLI, "npm-cli", false, "publish with the npm CLI instead of uploading to the registry directly")
 }
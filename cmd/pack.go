@@ -0,0 +1,56 @@
+/*
+Copyright © 2026 JACOB ARTHURS
+*/
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/jacobarthurs/shipbin/pkg/shipbin"
+	"github.com/spf13/cobra"
+)
+
+var flagOut string
+
+var packCmd = &cobra.Command{
+	Use:       "pack [target...]",
+	Short:     "Write installable packages to a directory instead of publishing",
+	ValidArgs: []string{"npm", "pypi"},
+	Long: `Builds the packages for every given target and writes them to --out
+instead of publishing them: a .tgz for each npm package, including the root
+package, and a .whl for each wheel. A manifest.json lists every file with its
+size and SHA-256.
+
+No credentials are needed, so the output can be inspected or tested locally:
+
+  npm install ./out/*.tgz
+  pip install ./out/*.whl
+
+Targets default to "targets" in the config file.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && projectFile != nil {
+			args = projectFile.Targets
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("no targets given: pass one or more of npm, pypi, or a plugin name")
+		}
+
+		cfg, err := releaseConfig(cmd, args)
+		if err != nil {
+			return err
+		}
+		files, err := shipbin.Pack(cmd.Context(), cfg, flagOut)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("pack: wrote %d files and %s to %s\n", len(files), shipbin.ManifestFile, filepath.Clean(flagOut))
+		return nil
+	},
+}
+
+func init() {
+	packCmd.Flags().StringVar(&flagOut, "out", "out", "directory to write packages and the manifest to")
+	addNpmFlags(packCmd)
+	addPypiFlags(packCmd)
+}
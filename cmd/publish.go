@@ -0,0 +1,132 @@
+/*
+Copyright © 2026 JACOB ARTHURS
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jacobarthurs/shipbin/pkg/shipbin"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagJournalDir  string
+	flagConcurrency int
+	flagRetries     int
+	flagTimeout     time.Duration
+)
+
+var publishCmd = &cobra.Command{
+	Use:       "publish [target...]",
+	Short:     "Publish binaries to several registries in one run",
+	ValidArgs: []string{"npm", "pypi"},
+	Long: `Publishes pre-built binaries to every given target.
+
+Targets are npm, pypi, or the name of a plugin: an executable called
+shipbin-publisher-<name> on PATH that speaks shipbin's JSON protocol.
+
+Artifacts and the version are resolved once. Every target's packages are
+built and validated, including credentials, before anything is uploaded, so a
+broken configuration for one registry doesn't leave a release half-published.
+Targets are then published in order, and a summary reports the outcome for
+each of them. Packages already published with the same contents are skipped,
+so a release that failed halfway can simply be run again.
+
+Targets can also be listed under "targets" in the config file. Flags of the
+npm and pypi commands apply to their targets.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && projectFile != nil {
+			args = projectFile.Targets
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("no targets given: pass one or more of npm, pypi, or a plugin name")
+		}
+		return runTargets(cmd, args)
+	},
+}
+
+func runTargets(cmd *cobra.Command, targets []string) error {
+	cfg, err := releaseConfig(cmd, targets)
+	if err != nil {
+		return err
+	}
+
+	results, err := shipbin.Publish(cmd.Context(), cfg)
+	if errors.Is(err, context.Canceled) {
+		return printInterrupted(results)
+	}
+	if results == nil || len(targets) == 1 {
+		if err != nil && len(targets) > 1 {
+			return fmt.Errorf("publish: nothing was published\n%w", err)
+		}
+		return err
+	}
+	return printSummary(results)
+}
+
+func printSummary(results []shipbin.Result) error {
+	fmt.Println("publish: summary")
+	var failed []string
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed = append(failed, r.Target)
+			fmt.Printf("  %-5s failed after publishing %d of %d: %v\n", r.Target, len(r.Published), len(r.Packages), r.Err)
+		case flagDryRun:
+			fmt.Printf("  %-5s would publish %d\n", r.Target, len(r.Packages))
+		case len(r.Skipped) > 0:
+			fmt.Printf("  %-5s published %d, skipped %d already published\n", r.Target, len(r.Published), len(r.Skipped))
+		default:
+			fmt.Printf("  %-5s published %d\n", r.Target, len(r.Published))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("publish: %s failed", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func printInterrupted(results []shipbin.Result) error {
+	if results == nil {
+		return fmt.Errorf("publish: interrupted before anything was uploaded")
+	}
+
+	fmt.Println("publish: interrupted")
+	for _, r := range results {
+		var missing []string
+		for _, pkg := range r.Packages {
+			if !slices.Contains(r.Published, pkg) && !slices.Contains(r.Skipped, pkg) {
+				missing = append(missing, pkg)
+			}
+		}
+		fmt.Printf("  %-5s published: %s\n", r.Target, listOrNone(r.Published))
+		fmt.Printf("  %-5s not published: %s\n", "", listOrNone(missing))
+	}
+	return fmt.Errorf("publish: interrupted: run the same command again to publish the rest")
+}
+
+func listOrNone(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+func init() {
+	addNpmFlags(publishCmd)
+	addPypiFlags(publishCmd)
+	addPublishFlags(publishCmd)
+}
+
+func addPublishFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&flagJournalDir, "journal-dir", ".shipbin", "directory for the journal that lets an interrupted release resume (empty to disable)")
+	cmd.Flags().IntVar(&flagConcurrency, "concurrency", 1, "number of packages of a target to upload at once")
+	cmd.Flags().IntVar(&flagRetries, "retries", 3, "times to retry a registry request that fails with a network error, 408, 429 or 5xx")
+	cmd.Flags().DurationVar(&flagTimeout, "timeout", 5*time.Minute, "timeout for each attempt of a registry request")
+}
@@ -4,11 +4,19 @@ Copyright © 2026 JACOB ARTHURS
 package cmd
 
 import (
-	"github.com/jacobarthurs/shipbin/internal/config"
-	"github.com/jacobarthurs/shipbin/internal/pypi"
+	"github.com/jacobarthurs/shipbin/pkg/shipbin"
 	"github.com/spf13/cobra"
 )
 
+var (
+	flagStrictVersion  bool
+	flagCompressedTags bool
+	flagSplitUniversal bool
+	flagRepository     string
+	flagRepositoryURL  string
+	flagMaxFileSize    int
+)
+
 var pypiCmd = &cobra.Command{
 	Use:   "pypi",
 	Short: "Publish binaries to PyPI",
@@ -16,39 +24,54 @@ var pypiCmd = &cobra.Command{
 
 Builds a platform-specific wheel for each artifact containing the binary and a
 Python shim that locates and executes it. Users install the package with pip and
-the correct wheel is resolved automatically based on their platform.`,
+the correct wheel is resolved automatically based on their platform.
+
+Wheels are uploaded to PyPI by default. Use --repository testpypi, a section
+name from ~/.pypirc, or --repository-url to publish to TestPyPI, devpi,
+Artifactory, Nexus, or any other index that implements the upload API.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := buildPypiConfig()
-		if err != nil {
-			return err
-		}
-		return pypi.Publish(cfg)
+		return runTargets(cmd, []string{"pypi"})
 	},
 }
 
-func buildPypiConfig() (*pypi.Config, error) {
-	version, err := config.ResolveVersion(flagVersion)
-	if err != nil {
-		return nil, err
-	}
-
-	artifacts, err := config.ParseArtifacts(flagArtifacts)
-	if err != nil {
-		return nil, err
+func buildPypiConfig(cmd *cobra.Command) shipbin.PyPIConfig {
+	if f := projectFile; f != nil {
+		if f.PyPI.StrictVersion != nil && !cmd.Flags().Changed("strict-version") {
+			flagStrictVersion = *f.PyPI.StrictVersion
+		}
+		if f.PyPI.CompressedTags != nil && !cmd.Flags().Changed("compressed-tags") {
+			flagCompressedTags = *f.PyPI.CompressedTags
+		}
+		if f.PyPI.SplitUniversal != nil && !cmd.Flags().Changed("split-universal") {
+			flagSplitUniversal = *f.PyPI.SplitUniversal
+		}
+		applyFileValue(cmd, "repository", &flagRepository, f.PyPI.Repository)
+		applyFileValue(cmd, "repository-url", &flagRepositoryURL, f.PyPI.RepositoryURL)
+		if f.PyPI.MaxFileSize > 0 && !cmd.Flags().Changed("max-file-size") {
+			flagMaxFileSize = f.PyPI.MaxFileSize
+		}
 	}
 
-	cfg := &pypi.Config{
-		Name:      flagName,
-		Version:   version,
-		Artifacts: artifacts,
-		Summary:   flagSummary,
-		License:   flagLicense,
-		Readme:    flagReadme,
-		DryRun:    flagDryRun,
+	return shipbin.PyPIConfig{
+		StrictVersion:  flagStrictVersion,
+		CompressedTags: flagCompressedTags,
+		SplitUniversal: flagSplitUniversal,
+		Repository:     flagRepository,
+		RepositoryURL:  flagRepositoryURL,
+		MaxFileSize:    int64(flagMaxFileSize) << 20,
 	}
-
-	return cfg, nil
 }
 
 func init() {
+	addPypiFlags(pypiCmd)
+	addPublishFlags(pypiCmd)
+}
+
+func addPypiFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&flagStrictVersion, "strict-version", false, "fail instead of warning when the version can't be converted to PEP 440 without losing information")
+	cmd.Flags().BoolVar(&flagCompressedTags, "compressed-tags", false, "also tag linux wheels with every older manylinux policy they satisfy (manylinux1, manylinux2010, manylinux2014)")
+	cmd.Flags().BoolVar(&flagSplitUniversal, "split-universal", false, "build separate x86_64 and arm64 wheels from a darwin/universal artifact instead of one universal2 wheel")
+	cmd.Flags().StringVar(&flagRepository, "repository", "pypi", "repository to upload to: pypi, testpypi, or a section name from ~/.pypirc")
+	cmd.Flags().StringVar(&flagRepositoryURL, "repository-url", "", "upload endpoint of the repository (overrides --repository)")
+	cmd.Flags().IntVar(&flagMaxFileSize, "max-file-size", 0, "largest wheel to upload, in MB, for projects PyPI raised the limit for (default 100 on pypi and testpypi)")
 }
@@ -4,21 +4,36 @@ Copyright © 2026 JACOB ARTHURS
 package cmd
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
+	"github.com/jacobarthurs/shipbin/internal/config"
+	"github.com/jacobarthurs/shipbin/pkg/shipbin"
 	"github.com/spf13/cobra"
 )
 
 var (
 	flagName      string
 	flagArtifacts []string
+	flagBins      []string
+	flagIncludes  []string
 	flagVersion   string
 	flagSummary   string
 	flagLicense   string
 	flagDryRun    bool
 	flagReadme    string
+	flagConfig    string
+
+	flagFromGoReleaser string
+	flagSkipArchCheck  bool
 )
 
+var projectFile *config.File
+
 var rootCmd = &cobra.Command{
 	Use:          "shipbin",
 	SilenceUsage: true,
@@ -26,31 +41,162 @@ var rootCmd = &cobra.Command{
 	Long: `Publishes pre-built binaries to npm and PyPI.
 
 Assembles platform-specific packages from the provided artifacts,
-then publishes them to the target registry.`,
+then publishes them to the target registry.
+
+Settings can also be declared in a shipbin.yaml, shipbin.yml or shipbin.toml
+file in the current directory. Flags override values from the file.`,
+	PersistentPreRunE: loadProjectFile,
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVar(&flagName, "name", "", "binary name")
+	rootCmd.PersistentFlags().StringVar(&flagName, "name", "", "package name, also the binary name unless --bin is given")
+	rootCmd.PersistentFlags().StringArrayVar(&flagBins, "bin", nil, "executable to ship in each package (repeatable, defaults to --name)")
 	rootCmd.PersistentFlags().StringArrayVar(&flagArtifacts, "artifact", nil, "os/arch:path mapping (repeatable)")
+	rootCmd.PersistentFlags().StringArrayVar(&flagIncludes, "include", nil, "extra file to bundle as [os/arch:]path[=dest] (repeatable)")
 	rootCmd.PersistentFlags().StringVar(&flagVersion, "version", "", "release version (defaults to current git tag)")
 	rootCmd.PersistentFlags().StringVar(&flagSummary, "summary", "", "short description of the package (optional)")
 	rootCmd.PersistentFlags().StringVar(&flagLicense, "license", "", "license identifier (e.g. MIT, Apache-2.0)")
 	rootCmd.PersistentFlags().BoolVar(&flagDryRun, "dry-run", false, "print what would be published without publishing")
 	rootCmd.PersistentFlags().StringVar(&flagReadme, "readme", "", "path to README to include in the published package (optional)")
+	rootCmd.PersistentFlags().StringVar(&flagFromGoReleaser, "from-goreleaser", "", "read artifacts and version from a GoReleaser dist directory (e.g. dist/)")
+	rootCmd.PersistentFlags().BoolVar(&flagSkipArchCheck, "skip-arch-check", false, "don't verify that each binary's format and architecture match its declared platform")
+	rootCmd.PersistentFlags().StringVar(&flagConfig, "config", "", "path to a shipbin.yaml or shipbin.toml file (defaults to one in the current directory)")
 
-	if err := rootCmd.MarkPersistentFlagRequired("name"); err != nil {
-		panic(err)
-	}
-	if err := rootCmd.MarkPersistentFlagRequired("artifact"); err != nil {
-		panic(err)
-	}
+	rootCmd.MarkFlagsMutuallyExclusive("artifact", "from-goreleaser")
 
 	rootCmd.AddCommand(npmCmd)
 	rootCmd.AddCommand(pypiCmd)
+	rootCmd.AddCommand(publishCmd)
+	rootCmd.AddCommand(packCmd)
+}
+
+func loadProjectFile(cmd *cobra.Command, args []string) error {
+	path := flagConfig
+	if path == "" {
+		found, err := config.FindFile(".")
+		if err != nil {
+			return err
+		}
+		path = found
+	}
+
+	if path != "" {
+		f, err := config.LoadFile(path)
+		if err != nil {
+			return err
+		}
+		projectFile = f
+
+		applyFileValue(cmd, "name", &flagName, f.Name)
+		applyFileValue(cmd, "version", &flagVersion, f.Version)
+		applyFileValue(cmd, "summary", &flagSummary, f.Summary)
+		applyFileValue(cmd, "license", &flagLicense, f.License)
+		applyFileValue(cmd, "readme", &flagReadme, f.ReadmePath())
+		applyFileValue(cmd, "from-goreleaser", &flagFromGoReleaser, f.GoReleaserDir())
+		if len(f.Bins) > 0 && !cmd.Flags().Changed("bin") {
+			flagBins = f.Bins
+		}
+		if f.SkipArchCheck && !cmd.Flags().Changed("skip-arch-check") {
+			flagSkipArchCheck = true
+		}
+	}
+
+	var missing []string
+	if flagName == "" {
+		missing = append(missing, "name")
+	}
+	if len(flagArtifacts) == 0 && flagFromGoReleaser == "" && (projectFile == nil || len(projectFile.Artifacts) == 0) {
+		missing = append(missing, "artifact")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf(`required flag(s) "%s" not set`, strings.Join(missing, `", "`))
+	}
+
+	return nil
+}
+
+func applyFileValue(cmd *cobra.Command, flag string, dst *string, value string) {
+	if value != "" && !cmd.Flags().Changed(flag) {
+		*dst = value
+	}
+}
+
+func releaseConfig(cmd *cobra.Command, targets []string) (shipbin.Config, error) {
+	cfg := shipbin.Config{
+		Name:           flagName,
+		Executables:    executables(),
+		Version:        flagVersion,
+		Summary:        flagSummary,
+		License:        flagLicense,
+		Readme:         flagReadme,
+		Artifacts:      flagArtifacts,
+		GoReleaserDist: flagFromGoReleaser,
+		Includes:       flagIncludes,
+		SkipArchCheck:  flagSkipArchCheck,
+		DryRun:         flagDryRun,
+		JournalDir:     flagJournalDir,
+		Concurrency:    flagConcurrency,
+		Retries:        flagRetries,
+		RequestTimeout: flagTimeout,
+		Targets:        targets,
+		OnEvent:        printEvent,
+	}
+	if flagRetries == 0 {
+		cfg.Retries = -1
+	}
+	if projectFile != nil {
+		cfg.ConfigFile = projectFile.Path
+		cfg.Plugins = projectFile.Plugins
+	}
+
+	for _, t := range targets {
+		switch t {
+		case "npm":
+			npmCfg, err := buildNpmConfig(cmd)
+			if err != nil {
+				return shipbin.Config{}, err
+			}
+			cfg.Npm = npmCfg
+		case "pypi":
+			cfg.PyPI = buildPypiConfig(cmd)
+		}
+	}
+	return cfg, nil
+}
+
+func printEvent(e shipbin.Event) {
+	switch e.Kind {
+	case shipbin.EventPublished:
+	case shipbin.EventWarning:
+		fmt.Printf("%s: warning: %s\n", e.Target, e.Message)
+	default:
+		fmt.Printf("%s: %s\n", e.Target, e.Message)
+	}
+}
+
+func executables() []string {
+	if len(flagBins) == 0 {
+		return []string{flagName}
+	}
+	return flagBins
 }
 
 func Execute() {
-	err := rootCmd.Execute()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The first signal stops new uploads and lets shipbin clean up; a second
+	// one gets the default behavior and kills the process.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		fmt.Fprintf(os.Stderr, "\nshipbin: received %s, stopping and cleaning up (press Ctrl-C again to quit immediately)\n", sig)
+		cancel()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
 	if err != nil {
 		os.Exit(1)
 	}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var archiveSuffixes = []string{".tar.gz", ".tgz", ".tar", ".zip"}
+
+func splitArchivePath(p string) (archive, inner string, ok bool) {
+	lower := strings.ToLower(p)
+	for _, suffix := range archiveSuffixes {
+		if i := strings.Index(lower, suffix+"#"); i >= 0 {
+			end := i + len(suffix)
+			return p[:end], p[end+1:], true
+		}
+		if strings.HasSuffix(lower, suffix) {
+			return p, "", true
+		}
+	}
+	return "", "", false
+}
+
+type archiveEntry struct {
+	name string
+	mode os.FileMode
+	open func() (io.ReadCloser, error)
+}
+
+func extractBinary(archive, inner string, names []string, destDir string) (string, error) {
+	match := func(name string) bool {
+		name = strings.TrimPrefix(path.Clean("/"+name), "/")
+		if inner != "" {
+			return name == strings.TrimPrefix(path.Clean("/"+inner), "/")
+		}
+		for _, n := range names {
+			if path.Base(name) == n {
+				return true
+			}
+		}
+		return false
+	}
+
+	var found *archiveEntry
+	var dest string
+	visit := func(e archiveEntry) error {
+		if !match(e.name) {
+			return nil
+		}
+		if found != nil {
+			return fmt.Errorf("archive contains multiple matches (%s, %s): select one with #path/inside", found.name, e.name)
+		}
+		found = &e
+		dest = filepath.Join(destDir, path.Base(e.name))
+		return extractEntry(e, dest)
+	}
+
+	var err error
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		err = walkZip(archive, visit)
+	} else {
+		err = walkTar(archive, visit)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if found == nil {
+		if inner != "" {
+			return "", fmt.Errorf("%q not found in archive", inner)
+		}
+		return "", fmt.Errorf("no file named %s found in archive: select one with #path/inside", strings.Join(names, " or "))
+	}
+	return dest, nil
+}
+
+func extractEntry(e archiveEntry, dest string) error {
+	in, err := e.open()
+	if err != nil {
+		return fmt.Errorf("failed to read %s from archive: %w", e.name, err)
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, e.mode)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", e.name, err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(dest, e.mode)
+}
+
+func walkZip(archive string, visit func(archiveEntry) error) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		if err := visit(archiveEntry{name: f.Name, mode: f.Mode().Perm(), open: f.Open}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkTar(archive string, visit func(archiveEntry) error) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	var r io.Reader = f
+	lower := strings.ToLower(archive)
+	if strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		e := archiveEntry{
+			name: hdr.Name,
+			mode: hdr.FileInfo().Mode().Perm(),
+			open: func() (io.ReadCloser, error) { return io.NopCloser(tr), nil },
+		}
+		if err := visit(e); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type archiveFile struct {
+	name string
+	mode os.FileMode
+	body string
+}
+
+func makeTarGz(t *testing.T, dir, name string, files ...archiveFile) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, af := range files {
+		hdr := &tar.Header{Name: af.name, Mode: int64(af.mode), Size: int64(len(af.body)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(af.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func makeZip(t *testing.T, dir, name string, files ...archiveFile) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	zw := zip.NewWriter(f)
+	for _, af := range files {
+		hdr := &zip.FileHeader{Name: af.name, Method: zip.Deflate}
+		hdr.SetMode(af.mode)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(af.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSplitArchivePath(t *testing.T) {
+	tests := []struct {
+		input       string
+		wantArchive string
+		wantInner   string
+		wantOK      bool
+	}{
+		{"dist/mytool.tar.gz", "dist/mytool.tar.gz", "", true},
+		{"dist/mytool.TGZ", "dist/mytool.TGZ", "", true},
+		{"dist/mytool.zip#bin/mytool.exe", "dist/mytool.zip", "bin/mytool.exe", true},
+		{"dist/mytool.tar#mytool", "dist/mytool.tar", "mytool", true},
+		{"dist/mytool", "", "", false},
+		{"dist/my#tool", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			archive, inner, ok := splitArchivePath(tt.input)
+			if archive != tt.wantArchive || inner != tt.wantInner || ok != tt.wantOK {
+				t.Errorf("splitArchivePath(%q) = (%q, %q, %v), want (%q, %q, %v)",
+					tt.input, archive, inner, ok, tt.wantArchive, tt.wantInner, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseArtifacts_TarGzByName(t *testing.T) {
+	dir := t.TempDir()
+	binary := string(fakeBinary(t, "linux/amd64"))
+	archive := makeTarGz(t, dir, "mytool_linux_amd64.tar.gz",
+		archiveFile{name: "README.md", mode: 0644, body: "readme"},
+		archiveFile{name: "mytool_linux_amd64/mytool", mode: 0750, body: binary},
+	)
+
+	artifacts, cleanup, err := ParseArtifacts([]string{"linux/amd64:" + archive}, ParseOptions{Executables: []string{"mytool"}})
+	if err != nil {
+		t.Fatalf("ParseArtifacts: %v", err)
+	}
+
+	path := artifacts[0].Executables[0].Path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("extracted binary not readable: %v", err)
+	}
+	if string(data) != binary {
+		t.Errorf("extracted content does not match archived binary")
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0750 {
+			t.Errorf("extracted mode = %04o, want 0750", info.Mode().Perm())
+		}
+	}
+
+	cleanup()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("cleanup should remove extracted binary, stat err = %v", err)
+	}
+}
+
+func TestParseArtifacts_ZipInnerPath(t *testing.T) {
+	dir := t.TempDir()
+	archive := makeZip(t, dir, "mytool_windows_amd64.zip",
+		archiveFile{name: "a/mytool.exe", mode: 0644, body: string(fakeBinary(t, "windows/amd64")) + "first"},
+		archiveFile{name: "b/mytool.exe", mode: 0644, body: string(fakeBinary(t, "windows/amd64")) + "second"},
+	)
+
+	artifacts, cleanup, err := ParseArtifacts([]string{"windows/amd64:" + archive + "#./b/mytool.exe"}, ParseOptions{Executables: []string{"mytool"}})
+	if err != nil {
+		t.Fatalf("ParseArtifacts: %v", err)
+	}
+	defer cleanup()
+
+	data, err := os.ReadFile(artifacts[0].Executables[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(data), "second") {
+		t.Errorf("extracted the wrong file, want b/mytool.exe")
+	}
+	if filepath.Base(artifacts[0].Executables[0].Path) != "mytool.exe" {
+		t.Errorf("extracted name = %q, want mytool.exe", filepath.Base(artifacts[0].Executables[0].Path))
+	}
+}
+
+func TestParseArtifacts_ArchiveAmbiguous(t *testing.T) {
+	dir := t.TempDir()
+	archive := makeZip(t, dir, "mytool.zip",
+		archiveFile{name: "a/mytool", mode: 0755, body: "first"},
+		archiveFile{name: "b/mytool", mode: 0755, body: "second"},
+	)
+
+	_, _, err := ParseArtifacts([]string{"linux/amd64:" + archive}, ParseOptions{Executables: []string{"mytool"}})
+	if err == nil {
+		t.Fatal("expected error for ambiguous archive, got nil")
+	}
+	checkErrContains(t, err, "multiple matches", "#path/inside")
+}
+
+func TestParseArtifacts_ArchiveMissingBinary(t *testing.T) {
+	dir := t.TempDir()
+	archive := makeTarGz(t, dir, "mytool.tgz", archiveFile{name: "LICENSE", mode: 0644, body: "MIT"})
+
+	_, _, err := ParseArtifacts([]string{"linux/amd64:" + archive}, ParseOptions{Executables: []string{"mytool"}})
+	if err == nil {
+		t.Fatal("expected error when binary is missing from archive, got nil")
+	}
+	checkErrContains(t, err, "no file named mytool")
+
+	_, _, err = ParseArtifacts([]string{"linux/amd64:" + archive + "#bin/mytool"}, ParseOptions{Executables: []string{"mytool"}})
+	if err == nil {
+		t.Fatal("expected error when inner path is missing, got nil")
+	}
+	checkErrContains(t, err, `"bin/mytool" not found`)
+}
+
+func TestParseArtifacts_ArchiveNotExecutable(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executable bit check is not enforced on Windows")
+	}
+	dir := t.TempDir()
+	archive := makeTarGz(t, dir, "mytool.tar.gz", archiveFile{name: "mytool", mode: 0644, body: "binary"})
+
+	_, _, err := ParseArtifacts([]string{"linux/amd64:" + archive}, ParseOptions{Executables: []string{"mytool"}})
+	if err == nil {
+		t.Fatal("expected error for non-executable binary in archive, got nil")
+	}
+	checkErrContains(t, err, "not executable")
+}
+
+func TestParseArtifacts_ArchivesPerPlatform(t *testing.T) {
+	dir := t.TempDir()
+	linux := makeTarGz(t, dir, "linux.tar.gz", archiveFile{name: "mytool", mode: 0755, body: string(fakeBinary(t, "linux/amd64"))})
+	darwin := makeTarGz(t, dir, "darwin.tar.gz", archiveFile{name: "mytool", mode: 0755, body: string(fakeBinary(t, "darwin/arm64"))})
+
+	artifacts, cleanup, err := ParseArtifacts([]string{
+		"linux/amd64:" + linux,
+		"darwin/arm64:" + darwin,
+	}, ParseOptions{Executables: []string{"mytool"}})
+	if err != nil {
+		t.Fatalf("ParseArtifacts: %v", err)
+	}
+	defer cleanup()
+
+	for i, want := range []string{"linux/amd64", "darwin/arm64"} {
+		data, err := os.ReadFile(artifacts[i].Executables[0].Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, fakeBinary(t, want)) {
+			t.Errorf("artifact %d content is not the %s binary", i, want)
+		}
+	}
+}
+
+func TestParseArtifacts_ArchiveMultipleExecutables(t *testing.T) {
+	dir := t.TempDir()
+	archive := makeTarGz(t, dir, "mytool.tar.gz",
+		archiveFile{name: "mytool", mode: 0755, body: string(fakeBinary(t, "linux/amd64")) + "main"},
+		archiveFile{name: "mytool-lsp", mode: 0755, body: string(fakeBinary(t, "linux/amd64")) + "lsp"},
+	)
+
+	artifacts, cleanup, err := ParseArtifacts([]string{"linux/amd64:" + archive}, ParseOptions{Executables: []string{"mytool", "mytool-lsp"}})
+	if err != nil {
+		t.Fatalf("ParseArtifacts: %v", err)
+	}
+	defer cleanup()
+
+	exes := artifacts[0].Executables
+	if len(exes) != 2 {
+		t.Fatalf("expected 2 executables from one archive, got %d", len(exes))
+	}
+	for i, want := range []string{"main", "lsp"} {
+		data, err := os.ReadFile(exes[i].Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(string(data), want) {
+			t.Errorf("%s content does not end with %q", exes[i].Name, want)
+		}
+	}
+}
@@ -5,71 +5,242 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/jacobarthurs/shipbin/internal/platforms"
 )
 
-var semverRe = regexp.MustCompile(`^\d+\.\d+\.\d+(-[a-zA-Z0-9][a-zA-Z0-9.-]*)?$`)
+var semverRe = regexp.MustCompile(`^\d+\.\d+\.\d+(-[a-zA-Z0-9][a-zA-Z0-9.-]*)?(\+[a-zA-Z0-9][a-zA-Z0-9.-]*)?$`)
 
 type Artifact struct {
-	Platform platforms.Platform
-	Mapping  platforms.Mapping
-	Path     string
+	Platform    platforms.Platform
+	Mapping     platforms.Mapping
+	Executables []Executable
+	Files       []ExtraFile
+	MinOS       OSVersion
 }
 
-func ParseArtifacts(artifacts []string) ([]Artifact, error) {
-	seen := make(map[platforms.Platform]string)
+type Executable struct {
+	Name string
+	Path string
+}
+
+type ParseOptions struct {
+	Executables   []string
+	Includes      []Include
+	SkipArchCheck bool
+}
+
+type executableKey struct {
+	platform platforms.Platform
+	name     string
+}
+
+func ParseArtifacts(artifacts []string, opts ParseOptions) ([]Artifact, func(), error) {
+	return parseArtifacts(artifacts, "", opts, func(_ int, entry string) string {
+		return fmt.Sprintf("--artifact %q", entry)
+	})
+}
+
+func parseArtifacts(artifacts []string, dir string, opts ParseOptions, label func(i int, entry string) string) ([]Artifact, func(), error) {
+	seen := make(map[executableKey]string)
+	index := make(map[platforms.Platform]int)
 	var results []Artifact
 	var errs []error
 
-	for _, entry := range artifacts {
+	var scratch string
+	cleanup := func() {
+		if scratch != "" {
+			_ = os.RemoveAll(scratch)
+		}
+	}
+
+	var primary string
+	if len(opts.Executables) > 0 {
+		primary = opts.Executables[0]
+	}
+
+	for i, entry := range artifacts {
+		src := label(i, entry)
+
 		platformStr, path, ok := strings.Cut(entry, ":")
 		if !ok {
-			errs = append(errs, fmt.Errorf("invalid --artifact %q: expected os/arch:path", entry))
+			errs = append(errs, fmt.Errorf("invalid %s: expected os/arch:path", src))
 			continue
 		}
 
-		goos, goarch, ok := strings.Cut(platformStr, "/")
-		if !ok {
-			errs = append(errs, fmt.Errorf("invalid --artifact %q: platform must be os/arch", entry))
+		exe, platformStr, named := strings.Cut(platformStr, "=")
+		if !named {
+			platformStr, exe = exe, ""
+		} else if !slices.Contains(opts.Executables, exe) {
+			errs = append(errs, fmt.Errorf("%s: executable %q is not declared (declared: %s)", src, exe, strings.Join(opts.Executables, ", ")))
 			continue
 		}
 
-		m, err := platforms.Lookup(goos, goarch)
+		p, err := platforms.Parse(platformStr)
 		if err != nil {
-			errs = append(errs, fmt.Errorf("--artifact %q: %w", entry, err))
+			errs = append(errs, fmt.Errorf("invalid %s: %w", src, err))
 			continue
 		}
 
-		p := platforms.Platform{GOOS: goos, GOARCH: goarch}
+		m, err := platforms.Lookup(p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", src, err))
+			continue
+		}
+		goos := p.GOOS
+
+		archive, inner, isArchive := splitArchivePath(path)
+		if isArchive {
+			path = archive
+		}
+		if dir != "" && !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		names := []string{primary}
+		switch {
+		case named:
+			names = []string{exe}
+		case isArchive && inner == "" && len(opts.Executables) > 0:
+			names = opts.Executables
+		}
 
-		if prev, ok := seen[p]; ok {
-			errs = append(errs, fmt.Errorf("duplicate --artifact for %s/%s: %q and %q", goos, goarch, prev, entry))
+		var duplicate bool
+		for _, name := range names {
+			key := executableKey{platform: p, name: name}
+			if prev, ok := seen[key]; ok {
+				errs = append(errs, fmt.Errorf("duplicate artifact for %s%s: %s and %s", p, exeSuffix(name, opts), prev, src))
+				duplicate = true
+				continue
+			}
+			seen[key] = src
+		}
+		if duplicate {
 			continue
 		}
-		seen[p] = entry
 
 		info, err := os.Stat(path)
 		if err != nil {
-			errs = append(errs, fmt.Errorf("--artifact %q: %w", entry, err))
+			errs = append(errs, fmt.Errorf("%s: %w", src, err))
 			continue
 		}
 		if info.IsDir() {
-			errs = append(errs, fmt.Errorf("--artifact %q: path is a directory, not a file", entry))
+			errs = append(errs, fmt.Errorf("%s: path is a directory, not a file", src))
 			continue
 		}
-		if runtime.GOOS != "windows" && goos != "windows" && info.Mode()&0111 == 0 {
-			errs = append(errs, fmt.Errorf("--artifact %q: file is not executable", entry))
+
+		var destDir string
+		if isArchive {
+			if scratch == "" {
+				scratch, err = os.MkdirTemp("", "shipbin-extract-*")
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to create scratch dir for archives: %w", err)
+				}
+			}
+			destDir = filepath.Join(scratch, fmt.Sprintf("%d-%s", i, strings.ReplaceAll(p.String(), "/", "-")))
+			if err := os.MkdirAll(destDir, 0755); err != nil {
+				cleanup()
+				return nil, nil, fmt.Errorf("failed to create scratch dir for archives: %w", err)
+			}
+		}
+
+		for _, name := range names {
+			exePath := path
+			if isArchive {
+				exePath, err = extractBinary(path, inner, binaryNames(name, goos), destDir)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", src, err))
+					continue
+				}
+			}
+
+			exeInfo, err := os.Stat(exePath)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", src, err))
+				continue
+			}
+			if runtime.GOOS != "windows" && goos != "windows" && exeInfo.Mode()&0111 == 0 {
+				if isArchive {
+					errs = append(errs, fmt.Errorf("%s: %s is not executable", src, filepath.Base(exePath)))
+				} else {
+					errs = append(errs, fmt.Errorf("%s: file is not executable", src))
+				}
+				continue
+			}
+			if !opts.SkipArchCheck {
+				if err := checkArchitecture(exePath, p); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w (use --skip-arch-check to bypass)", src, err))
+					continue
+				}
+			}
+
+			idx, ok := index[p]
+			if !ok {
+				idx = len(results)
+				index[p] = idx
+				results = append(results, Artifact{Platform: p, Mapping: m})
+			}
+			results[idx].Executables = append(results[idx].Executables, Executable{Name: name, Path: exePath})
+			if goos == "darwin" {
+				if v, ok := minMacOS(exePath, p.GOARCH); ok && results[idx].MinOS.Less(v) {
+					results[idx].MinOS = v
+				}
+			}
+		}
+	}
+
+	for _, a := range results {
+		if !a.Platform.IsUniversal() {
 			continue
 		}
+		for _, s := range a.Platform.Slices() {
+			if _, ok := index[s]; ok {
+				errs = append(errs, fmt.Errorf("%s overlaps %s: ship either the universal binary or per-architecture binaries", a.Platform, s))
+			}
+		}
+	}
+
+	for i := range results {
+		a := &results[i]
+		for _, name := range opts.Executables {
+			if _, ok := seen[executableKey{platform: a.Platform, name: name}]; !ok {
+				errs = append(errs, fmt.Errorf("%s: no artifact for executable %q (add --artifact %s=%s:path)",
+					a.Platform, name, name, a.Platform))
+			}
+		}
+		slices.SortStableFunc(a.Executables, func(x, y Executable) int {
+			return slices.Index(opts.Executables, x.Name) - slices.Index(opts.Executables, y.Name)
+		})
+	}
 
-		results = append(results, Artifact{Platform: p, Mapping: m, Path: path})
+	if err := attachIncludes(results, opts.Includes); err != nil {
+		errs = append(errs, err)
 	}
 
-	return results, errors.Join(errs...)
+	if err := errors.Join(errs...); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return results, cleanup, nil
+}
+
+func exeSuffix(name string, opts ParseOptions) string {
+	if len(opts.Executables) < 2 {
+		return ""
+	}
+	return fmt.Sprintf(" (%s)", name)
+}
+
+func binaryNames(binary, goos string) []string {
+	if goos == "windows" {
+		return []string{binary + ".exe", binary}
+	}
+	return []string{binary}
 }
 
 func ResolveVersion(explicit string) (string, error) {
@@ -97,7 +268,7 @@ func ResolveVersion(explicit string) (string, error) {
 func normalizeVersion(v string) (string, error) {
 	v = strings.TrimPrefix(v, "v")
 	if !semverRe.MatchString(v) {
-		return "", fmt.Errorf("invalid version %q: must be valid semver (e.g. 1.2.3, 1.2.3-beta.1)", v)
+		return "", fmt.Errorf("invalid version %q: must be valid semver (e.g. 1.2.3, 1.2.3-beta.1, 1.2.3+build.5)", v)
 	}
 	return v, nil
 }
@@ -8,14 +8,14 @@ import (
 	"testing"
 )
 
-func makeExe(t *testing.T, dir, name string) string {
+func makeExe(t *testing.T, dir, name, platform string) string {
 	t.Helper()
 	path := filepath.Join(dir, name)
 	mode := os.FileMode(0755)
 	if runtime.GOOS == "windows" {
 		mode = 0644
 	}
-	if err := os.WriteFile(path, []byte("fake binary"), mode); err != nil {
+	if err := os.WriteFile(path, fakeBinary(t, platform), mode); err != nil {
 		t.Fatalf("failed to create test binary: %v", err)
 	}
 	return path
@@ -23,20 +23,21 @@ func makeExe(t *testing.T, dir, name string) string {
 
 func TestParseArtifacts_Valid(t *testing.T) {
 	dir := t.TempDir()
-	p := makeExe(t, dir, "mytool")
+	p := makeExe(t, dir, "mytool", "linux/amd64")
 
-	artifacts, err := ParseArtifacts([]string{"linux/amd64:" + p})
+	artifacts, cleanup, err := ParseArtifacts([]string{"linux/amd64:" + p}, ParseOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	defer cleanup()
 	if len(artifacts) != 1 {
 		t.Fatalf("expected 1 artifact, got %d", len(artifacts))
 	}
 	if artifacts[0].Platform.GOOS != "linux" || artifacts[0].Platform.GOARCH != "amd64" {
 		t.Errorf("unexpected platform: %+v", artifacts[0].Platform)
 	}
-	if artifacts[0].Path != p {
-		t.Errorf("path = %q, want %q", artifacts[0].Path, p)
+	if artifacts[0].Executables[0].Path != p {
+		t.Errorf("path = %q, want %q", artifacts[0].Executables[0].Path, p)
 	}
 	if artifacts[0].Mapping.Npm.PackageSuffix != "linux-x64" {
 		t.Errorf("npm PackageSuffix = %q, want %q", artifacts[0].Mapping.Npm.PackageSuffix, "linux-x64")
@@ -45,37 +46,92 @@ func TestParseArtifacts_Valid(t *testing.T) {
 
 func TestParseArtifacts_MultipleValid(t *testing.T) {
 	dir := t.TempDir()
-	p1 := makeExe(t, dir, "bin-linux")
-	p2 := makeExe(t, dir, "bin-darwin")
+	p1 := makeExe(t, dir, "bin-linux", "linux/amd64")
+	p2 := makeExe(t, dir, "bin-darwin", "darwin/arm64")
 
-	artifacts, err := ParseArtifacts([]string{
+	artifacts, cleanup, err := ParseArtifacts([]string{
 		"linux/amd64:" + p1,
 		"darwin/arm64:" + p2,
-	})
+	}, ParseOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	defer cleanup()
 	if len(artifacts) != 2 {
 		t.Errorf("expected 2 artifacts, got %d", len(artifacts))
 	}
 }
 
+func TestParseArtifacts_ArmVariants(t *testing.T) {
+	dir := t.TempDir()
+	v6 := makeExe(t, dir, "mytool-armv6", "linux/arm")
+	v7 := makeExe(t, dir, "mytool-armv7", "linux/arm")
+
+	artifacts, cleanup, err := ParseArtifacts([]string{
+		"linux/arm/v6:" + v6,
+		"linux/arm:" + v7,
+	}, ParseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cleanup()
+	if len(artifacts) != 2 {
+		t.Fatalf("expected 2 artifacts, got %d", len(artifacts))
+	}
+	if got := artifacts[0].Platform.String(); got != "linux/arm/v6" {
+		t.Errorf("artifacts[0] platform = %s, want linux/arm/v6", got)
+	}
+	if got := artifacts[1].Platform.String(); got != "linux/arm/v7" {
+		t.Errorf("artifacts[1] platform = %s, want linux/arm/v7 (default variant)", got)
+	}
+	if artifacts[0].Mapping.Npm.PackageSuffix == artifacts[1].Mapping.Npm.PackageSuffix {
+		t.Errorf("arm variants should map to different npm packages, both got %q", artifacts[0].Mapping.Npm.PackageSuffix)
+	}
+
+	_, _, err = ParseArtifacts([]string{"linux/arm/v5:" + v6}, ParseOptions{})
+	if err == nil {
+		t.Fatal("expected error for unsupported arm variant, got nil")
+	}
+	checkErrContains(t, err, "unsupported arm variant")
+}
+
+func TestParseArtifacts_Musl(t *testing.T) {
+	dir := t.TempDir()
+	gnu := makeExe(t, dir, "mytool-gnu", "linux/amd64")
+	musl := makeExe(t, dir, "mytool-musl", "linux-musl/amd64")
+
+	artifacts, cleanup, err := ParseArtifacts([]string{
+		"linux/amd64:" + gnu,
+		"linux-musl/amd64:" + musl,
+	}, ParseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cleanup()
+	if len(artifacts) != 2 {
+		t.Fatalf("expected glibc and musl artifacts to be separate, got %d", len(artifacts))
+	}
+	if artifacts[1].Platform.Libc != "musl" || artifacts[1].Mapping.PyPI.WheelTag != "musllinux_1_2_x86_64" {
+		t.Errorf("unexpected musl artifact: %+v", artifacts[1])
+	}
+}
+
 func TestParseArtifacts_MissingColon(t *testing.T) {
-	_, err := ParseArtifacts([]string{"linux/amd64/path/to/bin"})
+	_, _, err := ParseArtifacts([]string{"linux/amd64/path/to/bin"}, ParseOptions{})
 	if err == nil {
 		t.Fatal("expected error for missing colon, got nil")
 	}
 }
 
 func TestParseArtifacts_MissingSlash(t *testing.T) {
-	_, err := ParseArtifacts([]string{"linuxamd64:/some/path"})
+	_, _, err := ParseArtifacts([]string{"linuxamd64:/some/path"}, ParseOptions{})
 	if err == nil {
 		t.Fatal("expected error for missing slash in platform, got nil")
 	}
 }
 
 func TestParseArtifacts_UnsupportedPlatform(t *testing.T) {
-	_, err := ParseArtifacts([]string{"freebsd/amd64:/some/path"})
+	_, _, err := ParseArtifacts([]string{"plan9/amd64:/some/path"}, ParseOptions{})
 	if err == nil {
 		t.Fatal("expected error for unsupported platform, got nil")
 	}
@@ -85,7 +141,7 @@ func TestParseArtifacts_UnsupportedPlatform(t *testing.T) {
 }
 
 func TestParseArtifacts_FileNotFound(t *testing.T) {
-	_, err := ParseArtifacts([]string{"linux/amd64:/does/not/exist/binary"})
+	_, _, err := ParseArtifacts([]string{"linux/amd64:/does/not/exist/binary"}, ParseOptions{})
 	if err == nil {
 		t.Fatal("expected error for missing file, got nil")
 	}
@@ -93,7 +149,7 @@ func TestParseArtifacts_FileNotFound(t *testing.T) {
 
 func TestParseArtifacts_IsDirectory(t *testing.T) {
 	dir := t.TempDir()
-	_, err := ParseArtifacts([]string{"linux/amd64:" + dir})
+	_, _, err := ParseArtifacts([]string{"linux/amd64:" + dir}, ParseOptions{})
 	if err == nil {
 		t.Fatal("expected error when path is a directory, got nil")
 	}
@@ -111,7 +167,7 @@ func TestParseArtifacts_NotExecutable(t *testing.T) {
 	if err := os.WriteFile(path, []byte("binary"), 0644); err != nil {
 		t.Fatal(err)
 	}
-	_, err := ParseArtifacts([]string{"linux/amd64:" + path})
+	_, _, err := ParseArtifacts([]string{"linux/amd64:" + path}, ParseOptions{})
 	if err == nil {
 		t.Fatal("expected error for non-executable file, got nil")
 	}
@@ -126,10 +182,10 @@ func TestParseArtifacts_WindowsTargetSkipsExeCheck(t *testing.T) {
 	}
 	dir := t.TempDir()
 	path := filepath.Join(dir, "tool.exe")
-	if err := os.WriteFile(path, []byte("binary"), 0644); err != nil {
+	if err := os.WriteFile(path, fakeBinary(t, "windows/amd64"), 0644); err != nil {
 		t.Fatal(err)
 	}
-	_, err := ParseArtifacts([]string{"windows/amd64:" + path})
+	_, _, err := ParseArtifacts([]string{"windows/amd64:" + path}, ParseOptions{})
 	if err != nil {
 		t.Fatalf("expected no error for windows target with no exe bit, got: %v", err)
 	}
@@ -137,11 +193,11 @@ func TestParseArtifacts_WindowsTargetSkipsExeCheck(t *testing.T) {
 
 func TestParseArtifacts_DuplicatePlatform(t *testing.T) {
 	dir := t.TempDir()
-	p := makeExe(t, dir, "bin")
-	_, err := ParseArtifacts([]string{
+	p := makeExe(t, dir, "bin", "linux/amd64")
+	_, _, err := ParseArtifacts([]string{
 		"linux/amd64:" + p,
 		"linux/amd64:" + p,
-	})
+	}, ParseOptions{})
 	if err == nil {
 		t.Fatal("expected error for duplicate platform, got nil")
 	}
@@ -151,10 +207,10 @@ func TestParseArtifacts_DuplicatePlatform(t *testing.T) {
 }
 
 func TestParseArtifacts_CollectsMultipleErrors(t *testing.T) {
-	_, err := ParseArtifacts([]string{
+	_, _, err := ParseArtifacts([]string{
 		"bad-entry-one",
 		"bad-entry-two",
-	})
+	}, ParseOptions{})
 	if err == nil {
 		t.Fatal("expected errors, got nil")
 	}
@@ -168,10 +224,11 @@ func TestParseArtifacts_CollectsMultipleErrors(t *testing.T) {
 }
 
 func TestParseArtifacts_EmptyInput(t *testing.T) {
-	artifacts, err := ParseArtifacts([]string{})
+	artifacts, cleanup, err := ParseArtifacts([]string{}, ParseOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error for empty input: %v", err)
 	}
+	defer cleanup()
 	if len(artifacts) != 0 {
 		t.Errorf("expected 0 artifacts, got %d", len(artifacts))
 	}
@@ -186,6 +243,8 @@ func TestResolveVersion_ExplicitValid(t *testing.T) {
 		{"v1.2.3", "1.2.3"},
 		{"1.0.0-beta.1", "1.0.0-beta.1"},
 		{"v2.0.0-rc.1", "2.0.0-rc.1"},
+		{"1.0.0+build.5", "1.0.0+build.5"},
+		{"1.0.0-beta.1+sha.abc123", "1.0.0-beta.1+sha.abc123"},
 		{"0.0.1", "0.0.1"},
 	}
 	for _, tt := range tests {
@@ -209,6 +268,8 @@ func TestResolveVersion_ExplicitInvalid(t *testing.T) {
 		"abc",
 		"v1.0",
 		"1.2.3.4",
+		"1.2.3+",
+		"1.2.3-",
 	}
 	for _, v := range cases {
 		t.Run(v, func(t *testing.T) {
@@ -219,3 +280,80 @@ func TestResolveVersion_ExplicitInvalid(t *testing.T) {
 		})
 	}
 }
+
+func TestParseArtifacts_MultipleExecutables(t *testing.T) {
+	dir := t.TempDir()
+	mainPath := makeExe(t, dir, "mytool", "linux/amd64")
+	lsp := makeExe(t, dir, "mytool-lsp", "linux/amd64")
+
+	artifacts, cleanup, err := ParseArtifacts([]string{
+		"mytool-lsp=linux/amd64:" + lsp,
+		"linux/amd64:" + mainPath,
+	}, ParseOptions{Executables: []string{"mytool", "mytool-lsp"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cleanup()
+
+	if len(artifacts) != 1 {
+		t.Fatalf("expected executables grouped into 1 artifact, got %d", len(artifacts))
+	}
+	exes := artifacts[0].Executables
+	if len(exes) != 2 {
+		t.Fatalf("expected 2 executables, got %d", len(exes))
+	}
+	if exes[0].Name != "mytool" || exes[0].Path != mainPath {
+		t.Errorf("executables[0] = %+v, want mytool at %s", exes[0], mainPath)
+	}
+	if exes[1].Name != "mytool-lsp" || exes[1].Path != lsp {
+		t.Errorf("executables[1] = %+v, want mytool-lsp at %s", exes[1], lsp)
+	}
+}
+
+func TestParseArtifacts_MissingExecutable(t *testing.T) {
+	dir := t.TempDir()
+	p := makeExe(t, dir, "mytool", "linux/amd64")
+
+	_, _, err := ParseArtifacts([]string{"linux/amd64:" + p}, ParseOptions{Executables: []string{"mytool", "mytool-lsp"}})
+	if err == nil {
+		t.Fatal("expected error for missing executable, got nil")
+	}
+	checkErrContains(t, err, "linux/amd64", `"mytool-lsp"`)
+}
+
+func TestParseArtifacts_UndeclaredExecutable(t *testing.T) {
+	dir := t.TempDir()
+	p := makeExe(t, dir, "other", "linux/amd64")
+
+	_, _, err := ParseArtifacts([]string{"other=linux/amd64:" + p}, ParseOptions{Executables: []string{"mytool"}})
+	if err == nil {
+		t.Fatal("expected error for undeclared executable, got nil")
+	}
+	checkErrContains(t, err, `"other" is not declared`)
+}
+
+func TestParseArtifacts_DuplicateExecutable(t *testing.T) {
+	dir := t.TempDir()
+	p := makeExe(t, dir, "mytool", "linux/amd64")
+
+	_, _, err := ParseArtifacts([]string{
+		"linux/amd64:" + p,
+		"mytool=linux/amd64:" + p,
+	}, ParseOptions{Executables: []string{"mytool", "mytool-lsp"}})
+	if err == nil {
+		t.Fatal("expected error for duplicate executable, got nil")
+	}
+	checkErrContains(t, err, "duplicate artifact for linux/amd64 (mytool)")
+}
+
+func TestParseArtifacts_UniversalOverlap(t *testing.T) {
+	dir := t.TempDir()
+	universal := makeExe(t, dir, "mytool-universal", "darwin/universal")
+	arm64 := makeExe(t, dir, "mytool-arm64", "darwin/arm64")
+
+	_, _, err := ParseArtifacts([]string{"darwin/universal:" + universal, "darwin/arm64:" + arm64}, ParseOptions{})
+	if err == nil {
+		t.Fatal("expected error for overlapping universal and arm64 artifacts, got nil")
+	}
+	checkErrContains(t, err, "darwin/universal overlaps darwin/arm64")
+}
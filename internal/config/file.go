@@ -0,0 +1,183 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+var FileNames = []string{"shipbin.yaml", "shipbin.yml", "shipbin.toml"}
+
+var yamlUnknownFieldRe = regexp.MustCompile(`field (\S+) not found in type \S+`)
+
+type File struct {
+	Path string `yaml:"-" toml:"-"`
+
+	Name      string   `yaml:"name" toml:"name"`
+	Bins      []string `yaml:"bins" toml:"bins"`
+	Version   string   `yaml:"version" toml:"version"`
+	Summary   string   `yaml:"summary" toml:"summary"`
+	License   string   `yaml:"license" toml:"license"`
+	Readme    string   `yaml:"readme" toml:"readme"`
+	Artifacts []string `yaml:"artifacts" toml:"artifacts"`
+
+	GoReleaser    string   `yaml:"goreleaser" toml:"goreleaser"`
+	Include       []string `yaml:"include" toml:"include"`
+	SkipArchCheck bool     `yaml:"skip_arch_check" toml:"skip_arch_check"`
+	Targets       []string `yaml:"targets" toml:"targets"`
+
+	Npm     NpmFile                   `yaml:"npm" toml:"npm"`
+	PyPI    PyPIFile                  `yaml:"pypi" toml:"pypi"`
+	Plugins map[string]map[string]any `yaml:"plugins" toml:"plugins"`
+}
+
+type NpmFile struct {
+	Org        string `yaml:"org" toml:"org"`
+	Tag        string `yaml:"tag" toml:"tag"`
+	Provenance *bool  `yaml:"provenance" toml:"provenance"`
+	CLI        bool   `yaml:"cli" toml:"cli"`
+	Registry   string `yaml:"registry" toml:"registry"`
+	Access     string `yaml:"access" toml:"access"`
+}
+
+type PyPIFile struct {
+	StrictVersion  *bool  `yaml:"strict_version" toml:"strict_version"`
+	CompressedTags *bool  `yaml:"compressed_tags" toml:"compressed_tags"`
+	SplitUniversal *bool  `yaml:"split_universal" toml:"split_universal"`
+	Repository     string `yaml:"repository" toml:"repository"`
+	RepositoryURL  string `yaml:"repository_url" toml:"repository_url"`
+	MaxFileSize    int    `yaml:"max_file_size" toml:"max_file_size"`
+}
+
+func FindFile(dir string) (string, error) {
+	for _, name := range FileNames {
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return "", err
+		}
+		if !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", nil
+}
+
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	f := &File{Path: path}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(f); err != nil && !errors.Is(err, io.EOF) {
+			return nil, yamlError(path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		var errs []error
+		for _, key := range md.Undecoded() {
+			errs = append(errs, fmt.Errorf("%s: %s: unknown key", path, key))
+		}
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported config format, expected .yaml, .yml or .toml", path)
+	}
+
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) ParseArtifacts(opts ParseOptions) ([]Artifact, func(), error) {
+	return parseArtifacts(f.Artifacts, f.dir(), opts, func(i int, entry string) string {
+		return fmt.Sprintf("%s: artifacts[%d] %q", f.Path, i, entry)
+	})
+}
+
+func (f *File) ParseIncludes() ([]Include, error) {
+	return parseIncludes(f.Include, f.dir(), func(i int, entry string) string {
+		return fmt.Sprintf("%s: include[%d] %q", f.Path, i, entry)
+	})
+}
+
+func (f *File) ReadmePath() string {
+	return f.resolve(f.Readme)
+}
+
+func (f *File) GoReleaserDir() string {
+	return f.resolve(f.GoReleaser)
+}
+
+func (f *File) validate() error {
+	var errs []error
+
+	if len(f.Artifacts) > 0 && f.GoReleaser != "" {
+		errs = append(errs, fmt.Errorf("%s: artifacts and goreleaser are mutually exclusive", f.Path))
+	}
+
+	if f.Version != "" {
+		if _, err := normalizeVersion(f.Version); err != nil {
+			errs = append(errs, fmt.Errorf("%s: version: %w", f.Path, err))
+		}
+	}
+
+	if f.PyPI.MaxFileSize < 0 {
+		errs = append(errs, fmt.Errorf("%s: pypi.max_file_size: must not be negative (0 keeps the repository's default limit)", f.Path))
+	}
+
+	if f.Readme != "" {
+		if _, err := os.Stat(f.ReadmePath()); err != nil {
+			errs = append(errs, fmt.Errorf("%s: readme: %w", f.Path, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (f *File) dir() string {
+	return filepath.Dir(f.Path)
+}
+
+func (f *File) resolve(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(f.dir(), path)
+}
+
+func yamlError(path string, err error) error {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	errs := make([]error, 0, len(typeErr.Errors))
+	for _, msg := range typeErr.Errors {
+		msg = yamlUnknownFieldRe.ReplaceAllString(msg, "unknown key $1")
+		errs = append(errs, fmt.Errorf("%s: %s", path, msg))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,265 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadFile_YAML(t *testing.T) {
+	dir := t.TempDir()
+	makeExe(t, dir, "mytool-linux", "linux/amd64")
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# mytool"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := writeConfigFile(t, dir, "shipbin.yaml", `
+name: mytool
+version: v1.2.3
+summary: A handy tool
+license: MIT
+readme: README.md
+artifacts:
+  - linux/amd64:mytool-linux
+npm:
+  org: myorg
+  tag: next
+  provenance: false
+pypi:
+  strict_version: true
+  compressed_tags: true
+  split_universal: true
+  max_file_size: 300
+`)
+
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if f.Name != "mytool" {
+		t.Errorf("Name = %q, want %q", f.Name, "mytool")
+	}
+	if f.Summary != "A handy tool" || f.License != "MIT" {
+		t.Errorf("unexpected metadata: summary=%q license=%q", f.Summary, f.License)
+	}
+	if f.Npm.Org != "myorg" || f.Npm.Tag != "next" {
+		t.Errorf("unexpected npm settings: %+v", f.Npm)
+	}
+	if f.Npm.Provenance == nil || *f.Npm.Provenance {
+		t.Errorf("Npm.Provenance = %v, want false", f.Npm.Provenance)
+	}
+	if f.PyPI.StrictVersion == nil || !*f.PyPI.StrictVersion {
+		t.Errorf("PyPI.StrictVersion = %v, want true", f.PyPI.StrictVersion)
+	}
+	if f.PyPI.CompressedTags == nil || !*f.PyPI.CompressedTags {
+		t.Errorf("PyPI.CompressedTags = %v, want true", f.PyPI.CompressedTags)
+	}
+	if f.PyPI.SplitUniversal == nil || !*f.PyPI.SplitUniversal {
+		t.Errorf("PyPI.SplitUniversal = %v, want true", f.PyPI.SplitUniversal)
+	}
+	if f.PyPI.MaxFileSize != 300 {
+		t.Errorf("PyPI.MaxFileSize = %d, want 300", f.PyPI.MaxFileSize)
+	}
+	if got, want := f.ReadmePath(), filepath.Join(dir, "README.md"); got != want {
+		t.Errorf("ReadmePath() = %q, want %q", got, want)
+	}
+
+	artifacts, cleanup, err := f.ParseArtifacts(ParseOptions{})
+	if err != nil {
+		t.Fatalf("ParseArtifacts: %v", err)
+	}
+	defer cleanup()
+	if len(artifacts) != 1 {
+		t.Fatalf("expected 1 artifact, got %d", len(artifacts))
+	}
+	if want := filepath.Join(dir, "mytool-linux"); artifacts[0].Executables[0].Path != want {
+		t.Errorf("artifact path = %q, want %q (relative to config file)", artifacts[0].Executables[0].Path, want)
+	}
+}
+
+func TestLoadFile_TOML(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "shipbin.toml", `
+name = "mytool"
+license = "Apache-2.0"
+artifacts = ["darwin/arm64:bin/mytool"]
+
+[npm]
+org = "myorg"
+
+[pypi]
+strict_version = false
+`)
+
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if f.Name != "mytool" || f.License != "Apache-2.0" {
+		t.Errorf("unexpected values: name=%q license=%q", f.Name, f.License)
+	}
+	if len(f.Artifacts) != 1 || f.Artifacts[0] != "darwin/arm64:bin/mytool" {
+		t.Errorf("Artifacts = %v", f.Artifacts)
+	}
+	if f.Npm.Org != "myorg" {
+		t.Errorf("Npm.Org = %q, want %q", f.Npm.Org, "myorg")
+	}
+	if f.Npm.Provenance != nil {
+		t.Errorf("Npm.Provenance should be unset, got %v", *f.Npm.Provenance)
+	}
+	if f.PyPI.StrictVersion == nil || *f.PyPI.StrictVersion {
+		t.Errorf("PyPI.StrictVersion = %v, want false", f.PyPI.StrictVersion)
+	}
+}
+
+func TestLoadFile_UnknownKeys(t *testing.T) {
+	tests := []struct {
+		filename string
+		content  string
+	}{
+		{"shipbin.yaml", "name: mytool\nnpm:\n  organisation: myorg\n"},
+		{"shipbin.toml", "name = \"mytool\"\n[npm]\norganisation = \"myorg\"\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			path := writeConfigFile(t, t.TempDir(), tt.filename, tt.content)
+			_, err := LoadFile(path)
+			if err == nil {
+				t.Fatal("expected error for unknown key, got nil")
+			}
+			checkErrContains(t, err, path, "organisation", "unknown key")
+		})
+	}
+}
+
+func TestLoadFile_InvalidVersion(t *testing.T) {
+	path := writeConfigFile(t, t.TempDir(), "shipbin.yaml", "name: mytool\nversion: 1.0\n")
+	_, err := LoadFile(path)
+	if err == nil {
+		t.Fatal("expected error for invalid version, got nil")
+	}
+	checkErrContains(t, err, path, "version:")
+}
+
+func TestLoadFile_NegativeMaxFileSize(t *testing.T) {
+	path := writeConfigFile(t, t.TempDir(), "shipbin.yaml", "name: mytool\npypi:\n  max_file_size: -1\n")
+	_, err := LoadFile(path)
+	if err == nil {
+		t.Fatal("expected error for a negative max_file_size, got nil")
+	}
+	checkErrContains(t, err, path, "pypi.max_file_size: must not be negative")
+}
+
+func TestLoadFile_MissingReadme(t *testing.T) {
+	path := writeConfigFile(t, t.TempDir(), "shipbin.yaml", "name: mytool\nreadme: NOPE.md\n")
+	_, err := LoadFile(path)
+	if err == nil {
+		t.Fatal("expected error for missing readme, got nil")
+	}
+	checkErrContains(t, err, path, "readme:")
+}
+
+func TestLoadFile_UnsupportedExtension(t *testing.T) {
+	path := writeConfigFile(t, t.TempDir(), "shipbin.json", "{}")
+	if _, err := LoadFile(path); err == nil {
+		t.Fatal("expected error for unsupported extension, got nil")
+	}
+}
+
+func TestLoadFile_Empty(t *testing.T) {
+	path := writeConfigFile(t, t.TempDir(), "shipbin.yaml", "")
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error for empty file: %v", err)
+	}
+	if f.Name != "" || len(f.Artifacts) != 0 {
+		t.Errorf("expected zero values, got %+v", f)
+	}
+}
+
+func TestFile_ParseArtifactsErrorsPointAtKey(t *testing.T) {
+	dir := t.TempDir()
+	makeExe(t, dir, "ok", "linux/amd64")
+	path := writeConfigFile(t, dir, "shipbin.yaml", `
+artifacts:
+  - linux/amd64:ok
+  - bad-entry
+  - plan9/amd64:ok
+`)
+
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	_, _, err = f.ParseArtifacts(ParseOptions{})
+	if err == nil {
+		t.Fatal("expected errors, got nil")
+	}
+	checkErrContains(t, err, path+": artifacts[1]", path+": artifacts[2]", "unsupported platform")
+	if strings.Contains(err.Error(), "artifacts[0]") {
+		t.Errorf("valid entry should not be reported, got: %v", err)
+	}
+}
+
+func TestFindFile(t *testing.T) {
+	dir := t.TempDir()
+
+	got, err := FindFile(dir)
+	if err != nil {
+		t.Fatalf("FindFile: %v", err)
+	}
+	if got != "" {
+		t.Errorf("FindFile() in empty dir = %q, want empty", got)
+	}
+
+	writeConfigFile(t, dir, "shipbin.toml", "")
+	yamlPath := writeConfigFile(t, dir, "shipbin.yaml", "")
+
+	got, err = FindFile(dir)
+	if err != nil {
+		t.Fatalf("FindFile: %v", err)
+	}
+	if got != yamlPath {
+		t.Errorf("FindFile() = %q, want %q (yaml takes precedence)", got, yamlPath)
+	}
+}
+
+func checkErrContains(t *testing.T, err error, substrs ...string) {
+	t.Helper()
+	for _, sub := range substrs {
+		if !strings.Contains(err.Error(), sub) {
+			t.Errorf("error missing %q, got: %v", sub, err)
+		}
+	}
+}
+
+func TestLoadFile_GoReleaser(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "shipbin.yaml", "name: mytool\ngoreleaser: dist\n")
+
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if got, want := f.GoReleaserDir(), filepath.Join(dir, "dist"); got != want {
+		t.Errorf("GoReleaserDir() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadFile_GoReleaserWithArtifacts(t *testing.T) {
+	path := writeConfigFile(t, t.TempDir(), "shipbin.yaml", "goreleaser: dist\nartifacts:\n  - linux/amd64:bin\n")
+	_, err := LoadFile(path)
+	if err == nil {
+		t.Fatal("expected error when both artifacts and goreleaser are set, got nil")
+	}
+	checkErrContains(t, err, "mutually exclusive")
+}
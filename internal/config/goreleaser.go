@@ -0,0 +1,149 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jacobarthurs/shipbin/internal/platforms"
+)
+
+type GoReleaserDist struct {
+	Dir       string
+	Version   string
+	Artifacts []string
+	Skipped   []string
+}
+
+type goreleaserArtifact struct {
+	Path    string `json:"path"`
+	Goos    string `json:"goos"`
+	Goarch  string `json:"goarch"`
+	Goamd64 string `json:"goamd64"`
+	Goarm   string `json:"goarm"`
+	Type    string `json:"type"`
+	Extra   struct {
+		Binary string `json:"Binary"`
+	} `json:"extra"`
+}
+
+type goreleaserMetadata struct {
+	Tag     string `json:"tag"`
+	Version string `json:"version"`
+}
+
+func LoadGoReleaser(dir string, executables []string) (*GoReleaserDist, error) {
+	artifactsPath := filepath.Join(dir, "artifacts.json")
+	var entries []goreleaserArtifact
+	if err := readJSONFile(artifactsPath, &entries); err != nil {
+		return nil, err
+	}
+
+	d := &GoReleaserDist{Dir: dir}
+
+	var meta goreleaserMetadata
+	metadataPath := filepath.Join(dir, "metadata.json")
+	if err := readJSONFile(metadataPath, &meta); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	if v := meta.Version; v != "" || meta.Tag != "" {
+		if v == "" {
+			v = meta.Tag
+		}
+		version, err := normalizeVersion(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", metadataPath, err)
+		}
+		d.Version = version
+	}
+
+	universal := make(map[string]bool)
+	for _, e := range entries {
+		if e.Type == "Universal Binary" && e.Goos == "darwin" {
+			universal[e.Extra.Binary] = true
+		}
+	}
+
+	for _, e := range entries {
+		if !slices.Contains(executables, e.Extra.Binary) {
+			continue
+		}
+		switch {
+		case e.Type == "Universal Binary":
+			e.Goarch = "universal"
+		case e.Type != "Binary", e.Goos == "darwin" && universal[e.Extra.Binary]:
+			continue
+		}
+		if e.Goamd64 != "" && e.Goamd64 != "v1" {
+			d.Skipped = append(d.Skipped, fmt.Sprintf("%s/%s (GOAMD64=%s: only v1 builds run on every amd64 CPU)", e.Goos, e.Goarch, e.Goamd64))
+			continue
+		}
+		spec := e.Goos + "/" + e.Goarch
+		if e.Goarm != "" {
+			spec += "/v" + e.Goarm
+		}
+		p, err := platforms.Parse(spec)
+		if err != nil {
+			d.Skipped = append(d.Skipped, spec)
+			continue
+		}
+		if _, err := platforms.Lookup(p); err != nil {
+			d.Skipped = append(d.Skipped, p.String())
+			continue
+		}
+		d.Artifacts = append(d.Artifacts, fmt.Sprintf("%s=%s:%s", e.Extra.Binary, p, e.Path))
+	}
+
+	if len(d.Artifacts) == 0 {
+		return nil, fmt.Errorf("%s: no supported Binary artifacts named %s found", artifactsPath, strings.Join(executables, " or "))
+	}
+
+	return d, nil
+}
+
+func (d *GoReleaserDist) ParseArtifacts(opts ParseOptions) ([]Artifact, func(), error) {
+	artifactsPath := filepath.Join(d.Dir, "artifacts.json")
+	entries := make([]string, len(d.Artifacts))
+	for i, entry := range d.Artifacts {
+		spec, path, _ := strings.Cut(entry, ":")
+		entries[i] = spec + ":" + d.resolve(path)
+	}
+	return parseArtifacts(entries, "", opts, func(i int, _ string) string {
+		return fmt.Sprintf("%s: %q", artifactsPath, d.Artifacts[i])
+	})
+}
+
+// resolve finds an artifact under the dist directory. GoReleaser writes paths
+// relative to the project root, so they start with the last components of
+// the dist directory, such as build/dist/mytool_linux_arm64/mytool for a dist
+// directory of build/dist. Other relative paths are left to the working
+// directory.
+func (d *GoReleaserDist) resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	dist := strings.Split(filepath.ToSlash(filepath.Clean(d.Dir)), "/")
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
+	for n := min(len(dist), len(parts)-1); n > 0; n-- {
+		if slices.Equal(dist[len(dist)-n:], parts[:n]) {
+			return filepath.Join(append([]string{d.Dir}, parts[n:]...)...)
+		}
+	}
+	return path
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeGoReleaserDist(t *testing.T, root, artifacts, metadata string) string {
+	t.Helper()
+	dist := filepath.Join(root, "dist")
+	if err := os.MkdirAll(dist, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dist, "artifacts.json"), []byte(artifacts), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if metadata != "" {
+		if err := os.WriteFile(filepath.Join(dist, "metadata.json"), []byte(metadata), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dist
+}
+
+const goreleaserArtifactsJSON = `[
+  {"name": "mytool", "path": "dist/mytool_linux_amd64_v1/mytool", "goos": "linux", "goarch": "amd64", "goamd64": "v1", "type": "Binary", "extra": {"Binary": "mytool"}},
+  {"name": "mytool", "path": "dist/mytool_linux_amd64_v3/mytool", "goos": "linux", "goarch": "amd64", "goamd64": "v3", "type": "Binary", "extra": {"Binary": "mytool"}},
+  {"name": "mytool", "path": "dist/mytool_darwin_arm64/mytool", "goos": "darwin", "goarch": "arm64", "type": "Binary", "extra": {"Binary": "mytool"}},
+  {"name": "mytool", "path": "dist/mytool_plan9_amd64_v1/mytool", "goos": "plan9", "goarch": "amd64", "goamd64": "v1", "type": "Binary", "extra": {"Binary": "mytool"}},
+  {"name": "other", "path": "dist/other_linux_amd64_v1/other", "goos": "linux", "goarch": "amd64", "goamd64": "v1", "type": "Binary", "extra": {"Binary": "other"}},
+  {"name": "mytool_linux_amd64.tar.gz", "path": "dist/mytool_linux_amd64.tar.gz", "goos": "linux", "goarch": "amd64", "type": "Archive"}
+]`
+
+func TestLoadGoReleaser(t *testing.T) {
+	root := t.TempDir()
+	dist := writeGoReleaserDist(t, root, goreleaserArtifactsJSON, `{"project_name": "mytool", "tag": "v1.4.0", "version": "1.4.0"}`)
+
+	d, err := LoadGoReleaser(dist, []string{"mytool"})
+	if err != nil {
+		t.Fatalf("LoadGoReleaser: %v", err)
+	}
+	if d.Version != "1.4.0" {
+		t.Errorf("Version = %q, want %q", d.Version, "1.4.0")
+	}
+
+	want := []string{
+		"mytool=linux/amd64:dist/mytool_linux_amd64_v1/mytool",
+		"mytool=darwin/arm64:dist/mytool_darwin_arm64/mytool",
+	}
+	if strings.Join(d.Artifacts, ",") != strings.Join(want, ",") {
+		t.Errorf("Artifacts = %v, want %v", d.Artifacts, want)
+	}
+	if len(d.Skipped) != 2 || !strings.HasPrefix(d.Skipped[0], "linux/amd64 (GOAMD64=v3") || d.Skipped[1] != "plan9/amd64" {
+		t.Errorf("Skipped = %v, want linux/amd64 with GOAMD64=v3 and plan9/amd64", d.Skipped)
+	}
+}
+
+func TestLoadGoReleaser_ArmVariants(t *testing.T) {
+	root := t.TempDir()
+	dist := writeGoReleaserDist(t, root, `[
+  {"path": "dist/mytool_linux_arm_6/mytool", "goos": "linux", "goarch": "arm", "goarm": "6", "type": "Binary", "extra": {"Binary": "mytool"}},
+  {"path": "dist/mytool_linux_arm_7/mytool", "goos": "linux", "goarch": "arm", "goarm": "7", "type": "Binary", "extra": {"Binary": "mytool"}},
+  {"path": "dist/mytool_linux_arm_5/mytool", "goos": "linux", "goarch": "arm", "goarm": "5", "type": "Binary", "extra": {"Binary": "mytool"}}
+]`, "")
+
+	d, err := LoadGoReleaser(dist, []string{"mytool"})
+	if err != nil {
+		t.Fatalf("LoadGoReleaser: %v", err)
+	}
+	want := []string{
+		"mytool=linux/arm/v6:dist/mytool_linux_arm_6/mytool",
+		"mytool=linux/arm/v7:dist/mytool_linux_arm_7/mytool",
+	}
+	if strings.Join(d.Artifacts, ",") != strings.Join(want, ",") {
+		t.Errorf("Artifacts = %v, want %v", d.Artifacts, want)
+	}
+	if len(d.Skipped) != 1 || d.Skipped[0] != "linux/arm/v5" {
+		t.Errorf("Skipped = %v, want [linux/arm/v5]", d.Skipped)
+	}
+}
+
+func TestLoadGoReleaser_UniversalBinary(t *testing.T) {
+	dist := writeGoReleaserDist(t, t.TempDir(), `[
+  {"name": "mytool", "path": "dist/mytool_darwin_amd64_v1/mytool", "goos": "darwin", "goarch": "amd64", "goamd64": "v1", "type": "Binary", "extra": {"Binary": "mytool"}},
+  {"name": "mytool", "path": "dist/mytool_darwin_arm64/mytool", "goos": "darwin", "goarch": "arm64", "type": "Binary", "extra": {"Binary": "mytool"}},
+  {"name": "mytool", "path": "dist/mytool_darwin_all/mytool", "goos": "darwin", "goarch": "all", "type": "Universal Binary", "extra": {"Binary": "mytool", "Replaces": false}},
+  {"name": "mytool", "path": "dist/mytool_linux_arm64/mytool", "goos": "linux", "goarch": "arm64", "type": "Binary", "extra": {"Binary": "mytool"}}
+]`, "")
+
+	d, err := LoadGoReleaser(dist, []string{"mytool"})
+	if err != nil {
+		t.Fatalf("LoadGoReleaser: %v", err)
+	}
+	want := []string{
+		"mytool=darwin/universal:dist/mytool_darwin_all/mytool",
+		"mytool=linux/arm64:dist/mytool_linux_arm64/mytool",
+	}
+	if strings.Join(d.Artifacts, ",") != strings.Join(want, ",") {
+		t.Errorf("Artifacts = %v, want %v", d.Artifacts, want)
+	}
+	if len(d.Skipped) != 0 {
+		t.Errorf("Skipped = %v, want none", d.Skipped)
+	}
+}
+
+func TestLoadGoReleaser_VersionFromTag(t *testing.T) {
+	root := t.TempDir()
+	dist := writeGoReleaserDist(t, root, goreleaserArtifactsJSON, `{"tag": "v2.0.0-rc.1"}`)
+
+	d, err := LoadGoReleaser(dist, []string{"mytool"})
+	if err != nil {
+		t.Fatalf("LoadGoReleaser: %v", err)
+	}
+	if d.Version != "2.0.0-rc.1" {
+		t.Errorf("Version = %q, want %q", d.Version, "2.0.0-rc.1")
+	}
+}
+
+func TestLoadGoReleaser_MissingMetadata(t *testing.T) {
+	root := t.TempDir()
+	dist := writeGoReleaserDist(t, root, goreleaserArtifactsJSON, "")
+
+	d, err := LoadGoReleaser(dist, []string{"mytool"})
+	if err != nil {
+		t.Fatalf("LoadGoReleaser: %v", err)
+	}
+	if d.Version != "" {
+		t.Errorf("Version = %q, want empty", d.Version)
+	}
+}
+
+func TestLoadGoReleaser_InvalidVersion(t *testing.T) {
+	root := t.TempDir()
+	dist := writeGoReleaserDist(t, root, goreleaserArtifactsJSON, `{"version": "nightly"}`)
+
+	_, err := LoadGoReleaser(dist, []string{"mytool"})
+	if err == nil {
+		t.Fatal("expected error for invalid version, got nil")
+	}
+	checkErrContains(t, err, "metadata.json")
+}
+
+func TestLoadGoReleaser_NoMatchingBinary(t *testing.T) {
+	root := t.TempDir()
+	dist := writeGoReleaserDist(t, root, goreleaserArtifactsJSON, "")
+
+	_, err := LoadGoReleaser(dist, []string{"missing"})
+	if err == nil {
+		t.Fatal("expected error when no artifacts match, got nil")
+	}
+	checkErrContains(t, err, "artifacts.json", "named missing")
+}
+
+func TestLoadGoReleaser_MissingArtifactsJSON(t *testing.T) {
+	if _, err := LoadGoReleaser(t.TempDir(), []string{"mytool"}); err == nil {
+		t.Fatal("expected error for missing artifacts.json, got nil")
+	}
+}
+
+func TestGoReleaserDist_ParseArtifacts(t *testing.T) {
+	root := t.TempDir()
+	dist := writeGoReleaserDist(t, root, goreleaserArtifactsJSON, "")
+	for dir, platform := range map[string]string{"mytool_linux_amd64_v1": "linux/amd64", "mytool_darwin_arm64": "darwin/arm64"} {
+		if err := os.MkdirAll(filepath.Join(dist, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		makeExe(t, filepath.Join(dist, dir), "mytool", platform)
+	}
+
+	d, err := LoadGoReleaser(dist, []string{"mytool"})
+	if err != nil {
+		t.Fatalf("LoadGoReleaser: %v", err)
+	}
+	artifacts, cleanup, err := d.ParseArtifacts(ParseOptions{Executables: []string{"mytool"}})
+	if err != nil {
+		t.Fatalf("ParseArtifacts: %v", err)
+	}
+	defer cleanup()
+	if len(artifacts) != 2 {
+		t.Fatalf("expected 2 artifacts, got %d", len(artifacts))
+	}
+	if want := filepath.Join(dist, "mytool_linux_amd64_v1", "mytool"); artifacts[0].Executables[0].Path != want {
+		t.Errorf("path = %q, want %q (relative to project root)", artifacts[0].Executables[0].Path, want)
+	}
+}
+
+func TestGoReleaserDist_ParseArtifactsNestedDist(t *testing.T) {
+	root := t.TempDir()
+	dist := filepath.Join(root, "build", "dist")
+	if err := os.MkdirAll(filepath.Join(dist, "mytool_linux_arm64"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	artifacts := `[{"path": "build/dist/mytool_linux_arm64/mytool", "goos": "linux", "goarch": "arm64", "type": "Binary", "extra": {"Binary": "mytool"}}]`
+	if err := os.WriteFile(filepath.Join(dist, "artifacts.json"), []byte(artifacts), 0644); err != nil {
+		t.Fatal(err)
+	}
+	makeExe(t, filepath.Join(dist, "mytool_linux_arm64"), "mytool", "linux/arm64")
+
+	for _, tt := range []struct{ cwd, dir string }{
+		{root, filepath.Join("build", "dist")},
+		{t.TempDir(), dist},
+	} {
+		t.Chdir(tt.cwd)
+		d, err := LoadGoReleaser(tt.dir, []string{"mytool"})
+		if err != nil {
+			t.Fatalf("LoadGoReleaser(%s): %v", tt.dir, err)
+		}
+		artifacts, cleanup, err := d.ParseArtifacts(ParseOptions{Executables: []string{"mytool"}})
+		if err != nil {
+			t.Fatalf("ParseArtifacts(%s): %v", tt.dir, err)
+		}
+		cleanup()
+		if want := filepath.Join(tt.dir, "mytool_linux_arm64", "mytool"); artifacts[0].Executables[0].Path != want {
+			t.Errorf("path = %q, want %q", artifacts[0].Executables[0].Path, want)
+		}
+	}
+}
+
+func TestGoReleaserDist_ParseArtifactsMissingBinary(t *testing.T) {
+	root := t.TempDir()
+	dist := writeGoReleaserDist(t, root, goreleaserArtifactsJSON, "")
+
+	d, err := LoadGoReleaser(dist, []string{"mytool"})
+	if err != nil {
+		t.Fatalf("LoadGoReleaser: %v", err)
+	}
+	_, _, err = d.ParseArtifacts(ParseOptions{Executables: []string{"mytool"}})
+	if err == nil {
+		t.Fatal("expected error for missing binaries, got nil")
+	}
+	checkErrContains(t, err, "artifacts.json", "mytool_darwin_arm64")
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jacobarthurs/shipbin/internal/platforms"
+)
+
+var sharedLibrarySuffixes = []string{".so", ".dll", ".dylib"}
+
+type Include struct {
+	Platform *platforms.Platform
+	Src      string
+	Dest     string
+}
+
+type ExtraFile struct {
+	Src  string
+	Dest string
+}
+
+func ParseIncludes(includes []string) ([]Include, error) {
+	return parseIncludes(includes, "", func(_ int, entry string) string {
+		return fmt.Sprintf("--include %q", entry)
+	})
+}
+
+func parseIncludes(includes []string, dir string, label func(i int, entry string) string) ([]Include, error) {
+	var results []Include
+	var errs []error
+
+	for i, entry := range includes {
+		src := label(i, entry)
+		inc := Include{Src: entry}
+
+		if before, after, ok := strings.Cut(entry, ":"); ok && strings.Contains(before, "/") {
+			p, err := platforms.Parse(before)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s: %w", src, err))
+				continue
+			}
+			if _, err := platforms.Lookup(p); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", src, err))
+				continue
+			}
+			inc.Platform = &p
+			inc.Src = after
+		}
+
+		inc.Src, inc.Dest, _ = strings.Cut(inc.Src, "=")
+		if inc.Src == "" {
+			errs = append(errs, fmt.Errorf("invalid %s: expected [os/arch:]path[=dest]", src))
+			continue
+		}
+		if dir != "" && !filepath.IsAbs(inc.Src) {
+			inc.Src = filepath.Join(dir, inc.Src)
+		}
+
+		dest, err := includeDest(inc.Src, inc.Dest)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", src, err))
+			continue
+		}
+		inc.Dest = dest
+
+		info, err := os.Stat(inc.Src)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", src, err))
+			continue
+		}
+		if info.IsDir() {
+			errs = append(errs, fmt.Errorf("%s: path is a directory, not a file", src))
+			continue
+		}
+
+		results = append(results, inc)
+	}
+
+	return results, errors.Join(errs...)
+}
+
+func includeDest(src, dest string) (string, error) {
+	base := filepath.Base(src)
+	if dest == "" {
+		if isSharedLibrary(base) {
+			return "bin/" + base, nil
+		}
+		return "share/" + base, nil
+	}
+
+	if strings.HasSuffix(dest, "/") {
+		dest += base
+	}
+	cleaned := path.Clean(dest)
+	if cleaned != dest || (!strings.HasPrefix(dest, "bin/") && !strings.HasPrefix(dest, "share/")) {
+		return "", fmt.Errorf("destination %q must be a clean path under bin/ or share/", dest)
+	}
+	return dest, nil
+}
+
+func isSharedLibrary(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range sharedLibrarySuffixes {
+		if strings.HasSuffix(lower, suffix) || strings.Contains(lower, suffix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func attachIncludes(artifacts []Artifact, includes []Include) error {
+	var errs []error
+	for _, inc := range includes {
+		matched := false
+		for i := range artifacts {
+			a := &artifacts[i]
+			if inc.Platform != nil && *inc.Platform != a.Platform {
+				continue
+			}
+			matched = true
+			for _, exe := range a.Executables {
+				name := exe.Name
+				if a.Platform.GOOS == "windows" {
+					name += ".exe"
+				}
+				if exe.Name != "" && inc.Dest == "bin/"+name {
+					errs = append(errs, fmt.Errorf("%s: include %s would overwrite executable %s",
+						a.Platform, inc.Src, exe.Name))
+				}
+			}
+			for _, existing := range a.Files {
+				if existing.Dest == inc.Dest {
+					errs = append(errs, fmt.Errorf("%s: include %s and %s both write %s",
+						a.Platform, existing.Src, inc.Src, inc.Dest))
+				}
+			}
+			a.Files = append(a.Files, ExtraFile{Src: inc.Src, Dest: inc.Dest})
+		}
+		if !matched && inc.Platform != nil {
+			errs = append(errs, fmt.Errorf("include %s targets %s, which has no artifact",
+				inc.Src, inc.Platform))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseIncludes(t *testing.T) {
+	dir := t.TempDir()
+	license := writeFile(t, dir, "LICENSE")
+	lib := writeFile(t, dir, "libfoo.so.1")
+	dll := writeFile(t, dir, "foo.dll")
+	comp := writeFile(t, dir, "mytool.bash")
+
+	includes, err := ParseIncludes([]string{
+		license,
+		"linux/amd64:" + lib,
+		"windows/amd64:" + dll,
+		comp + "=share/completions/",
+	})
+	if err != nil {
+		t.Fatalf("ParseIncludes: %v", err)
+	}
+
+	tests := []struct {
+		wantSrc      string
+		wantDest     string
+		wantPlatform string
+	}{
+		{license, "share/LICENSE", ""},
+		{lib, "bin/libfoo.so.1", "linux/amd64"},
+		{dll, "bin/foo.dll", "windows/amd64"},
+		{comp, "share/completions/mytool.bash", ""},
+	}
+	if len(includes) != len(tests) {
+		t.Fatalf("expected %d includes, got %d", len(tests), len(includes))
+	}
+	for i, tt := range tests {
+		inc := includes[i]
+		if inc.Src != tt.wantSrc || inc.Dest != tt.wantDest {
+			t.Errorf("includes[%d] = %s -> %s, want %s -> %s", i, inc.Src, inc.Dest, tt.wantSrc, tt.wantDest)
+		}
+		platform := ""
+		if inc.Platform != nil {
+			platform = inc.Platform.GOOS + "/" + inc.Platform.GOARCH
+		}
+		if platform != tt.wantPlatform {
+			t.Errorf("includes[%d] platform = %q, want %q", i, platform, tt.wantPlatform)
+		}
+	}
+}
+
+func TestParseIncludes_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	license := writeFile(t, dir, "LICENSE")
+
+	cases := map[string]string{
+		"missing file":        filepath.Join(dir, "NOPE"),
+		"directory":           dir,
+		"unsupported":         "plan9/amd64:" + license,
+		"dest outside":        license + "=../LICENSE",
+		"dest not bin/share":  license + "=lib/LICENSE",
+		"dest not clean":      license + "=share/./LICENSE",
+		"empty source":        "=share/LICENSE",
+		"platform no source":  "linux/amd64:",
+		"dest absolute":       license + "=/share/LICENSE",
+		"dest at package top": license + "=LICENSE",
+	}
+	for name, entry := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseIncludes([]string{entry}); err == nil {
+				t.Errorf("ParseIncludes(%q) expected error, got nil", entry)
+			}
+		})
+	}
+}
+
+func TestParseArtifacts_AttachesIncludes(t *testing.T) {
+	dir := t.TempDir()
+	linux := makeExe(t, dir, "mytool-linux", "linux/amd64")
+	darwin := makeExe(t, dir, "mytool-darwin", "darwin/arm64")
+	includes, err := ParseIncludes([]string{
+		writeFile(t, dir, "LICENSE"),
+		"linux/amd64:" + writeFile(t, dir, "libfoo.so"),
+	})
+	if err != nil {
+		t.Fatalf("ParseIncludes: %v", err)
+	}
+
+	artifacts, cleanup, err := ParseArtifacts([]string{
+		"linux/amd64:" + linux,
+		"darwin/arm64:" + darwin,
+	}, ParseOptions{Includes: includes})
+	if err != nil {
+		t.Fatalf("ParseArtifacts: %v", err)
+	}
+	defer cleanup()
+
+	if len(artifacts[0].Files) != 2 {
+		t.Errorf("linux artifact should have 2 files, got %v", artifacts[0].Files)
+	}
+	if len(artifacts[1].Files) != 1 || artifacts[1].Files[0].Dest != "share/LICENSE" {
+		t.Errorf("darwin artifact should only have LICENSE, got %v", artifacts[1].Files)
+	}
+}
+
+func TestParseArtifacts_IncludeForMissingPlatform(t *testing.T) {
+	dir := t.TempDir()
+	linux := makeExe(t, dir, "mytool", "linux/amd64")
+	includes, err := ParseIncludes([]string{"windows/amd64:" + writeFile(t, dir, "foo.dll")})
+	if err != nil {
+		t.Fatalf("ParseIncludes: %v", err)
+	}
+
+	_, _, err = ParseArtifacts([]string{"linux/amd64:" + linux}, ParseOptions{Includes: includes})
+	if err == nil {
+		t.Fatal("expected error for include targeting a platform without artifact, got nil")
+	}
+	checkErrContains(t, err, "windows/amd64", "no artifact")
+}
+
+func TestParseArtifacts_IncludeConflicts(t *testing.T) {
+	dir := t.TempDir()
+	linux := makeExe(t, dir, "mytool-bin", "linux/amd64")
+	other := t.TempDir()
+	includes, err := ParseIncludes([]string{
+		writeFile(t, dir, "LICENSE"),
+		writeFile(t, other, "LICENSE"),
+		writeFile(t, dir, "notes") + "=bin/mytool",
+	})
+	if err != nil {
+		t.Fatalf("ParseIncludes: %v", err)
+	}
+
+	_, _, err = ParseArtifacts([]string{"linux/amd64:" + linux}, ParseOptions{Executables: []string{"mytool"}, Includes: includes})
+	if err == nil {
+		t.Fatal("expected errors for conflicting includes, got nil")
+	}
+	checkErrContains(t, err, "both write share/LICENSE", "overwrite executable mytool")
+}
+
+func TestFile_ParseIncludesRelativeToFile(t *testing.T) {
+	dir := t.TempDir()
+	license := writeFile(t, dir, "LICENSE")
+	path := writeConfigFile(t, dir, "shipbin.yaml", "include:\n  - LICENSE\n  - missing.txt\n")
+
+	f, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	_, err = f.ParseIncludes()
+	if err == nil {
+		t.Fatal("expected error for missing include, got nil")
+	}
+	checkErrContains(t, err, path+": include[1]")
+
+	f.Include = f.Include[:1]
+	includes, err := f.ParseIncludes()
+	if err != nil {
+		t.Fatalf("ParseIncludes: %v", err)
+	}
+	if includes[0].Src != license {
+		t.Errorf("Src = %q, want %q", includes[0].Src, license)
+	}
+}
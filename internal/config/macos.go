@@ -0,0 +1,122 @@
+package config
+
+import (
+	"debug/macho"
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/jacobarthurs/shipbin/internal/platforms"
+)
+
+const (
+	loadCmdVersionMinMacOSX macho.LoadCmd = 0x24
+	loadCmdBuildVersion     macho.LoadCmd = 0x32
+
+	machoPlatformMacOS = 1
+)
+
+type OSVersion struct {
+	Major int
+	Minor int
+}
+
+func (v OSVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+func (v OSVersion) IsZero() bool {
+	return v == OSVersion{}
+}
+
+func (v OSVersion) Less(o OSVersion) bool {
+	return v.Major < o.Major || (v.Major == o.Major && v.Minor < o.Minor)
+}
+
+func (a Artifact) Split() []Artifact {
+	if !a.Platform.IsUniversal() {
+		return []Artifact{a}
+	}
+	var result []Artifact
+	for _, p := range a.Platform.Slices() {
+		m, err := platforms.Lookup(p)
+		if err != nil {
+			continue
+		}
+		slice := a
+		slice.Platform, slice.Mapping = p, m
+		var minOS OSVersion
+		for _, exe := range a.Executables {
+			if v, ok := minMacOS(exe.Path, p.GOARCH); ok && minOS.Less(v) {
+				minOS = v
+			}
+		}
+		if !minOS.IsZero() {
+			slice.MinOS = minOS
+		}
+		result = append(result, slice)
+	}
+	return result
+}
+
+func minMacOS(path, goarch string) (OSVersion, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return OSVersion{}, false
+	}
+	defer func() { _ = f.Close() }()
+
+	var magic uint32
+	if err := binary.Read(f, binary.BigEndian, &magic); err != nil {
+		return OSVersion{}, false
+	}
+
+	var files []*macho.File
+	if magic == macho.MagicFat {
+		ff, err := macho.NewFatFile(f)
+		if err != nil {
+			return OSVersion{}, false
+		}
+		for _, a := range ff.Arches {
+			if goarch == "universal" || a.Cpu == machoCPUs[goarch] {
+				files = append(files, a.File)
+			}
+		}
+	} else if mf, err := macho.NewFile(f); err == nil {
+		files = append(files, mf)
+	}
+
+	var v OSVersion
+	var found bool
+	for _, mf := range files {
+		if sv, ok := machoMinOS(mf); ok {
+			found = true
+			if v.Less(sv) {
+				v = sv
+			}
+		}
+	}
+	return v, found
+}
+
+func machoMinOS(mf *macho.File) (OSVersion, bool) {
+	for _, l := range mf.Loads {
+		raw := l.Raw()
+		if len(raw) < 16 {
+			continue
+		}
+		switch macho.LoadCmd(mf.ByteOrder.Uint32(raw)) {
+		case loadCmdBuildVersion:
+			if mf.ByteOrder.Uint32(raw[8:]) == machoPlatformMacOS {
+				return decodeMachOVersion(mf.ByteOrder.Uint32(raw[12:])), true
+			}
+		case loadCmdVersionMinMacOSX:
+			return decodeMachOVersion(mf.ByteOrder.Uint32(raw[8:])), true
+		}
+	}
+	return OSVersion{}, false
+}
+
+func decodeMachOVersion(v uint32) OSVersion {
+	return OSVersion{Major: int(v >> 16), Minor: int(v >> 8 & 0xff)}
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"bytes"
+	"debug/macho"
+	"testing"
+)
+
+func fakeMachO(t *testing.T, goarch string, loads ...[]byte) []byte {
+	t.Helper()
+	var cmds bytes.Buffer
+	for _, l := range loads {
+		cmds.Write(l)
+	}
+	var buf bytes.Buffer
+	mustWrite(t, &buf, macho.FileHeader{
+		Magic: macho.Magic64, Cpu: machoCPUs[goarch], Type: macho.TypeExec,
+		Ncmd: uint32(len(loads)), Cmdsz: uint32(cmds.Len()),
+	})
+	mustWrite(t, &buf, uint32(0))
+	buf.Write(cmds.Bytes())
+	return buf.Bytes()
+}
+
+func buildVersionCmd(t *testing.T, platform uint32, major, minor int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	mustWrite(t, &buf, []uint32{uint32(loadCmdBuildVersion), 24, platform, uint32(major<<16 | minor<<8), 0, 0})
+	return buf.Bytes()
+}
+
+func versionMinCmd(t *testing.T, major, minor int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	mustWrite(t, &buf, []uint32{uint32(loadCmdVersionMinMacOSX), 16, uint32(major<<16 | minor<<8), 0})
+	return buf.Bytes()
+}
+
+func TestMinMacOS(t *testing.T) {
+	const iOS = 2
+	tests := []struct {
+		name   string
+		data   []byte
+		goarch string
+		want   OSVersion
+		ok     bool
+	}{
+		{"build version", fakeMachO(t, "arm64", buildVersionCmd(t, machoPlatformMacOS, 13, 3)), "arm64", OSVersion{13, 3}, true},
+		{"version min", fakeMachO(t, "amd64", versionMinCmd(t, 10, 12)), "amd64", OSVersion{10, 12}, true},
+		{"other platform", fakeMachO(t, "arm64", buildVersionCmd(t, iOS, 16, 0)), "arm64", OSVersion{}, false},
+		{"no load command", fakeBinary(t, "darwin/arm64"), "arm64", OSVersion{}, false},
+		{"not mach-o", fakeBinary(t, "linux/arm64"), "arm64", OSVersion{}, false},
+		{"universal", fakeFatBinary(t,
+			fakeMachO(t, "amd64", versionMinCmd(t, 10, 13)),
+			fakeMachO(t, "arm64", buildVersionCmd(t, machoPlatformMacOS, 12, 0)),
+		), "amd64", OSVersion{10, 13}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeBinary(t, t.TempDir(), "mytool", tt.data)
+			got, ok := minMacOS(path, tt.goarch)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("minMacOS() = %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseArtifacts_MinOS(t *testing.T) {
+	dir := t.TempDir()
+	arm64 := writeBinary(t, dir, "mytool-arm64", fakeMachO(t, "arm64", buildVersionCmd(t, machoPlatformMacOS, 14, 2)))
+	amd64 := writeBinary(t, dir, "mytool-amd64", fakeBinary(t, "darwin/amd64"))
+
+	artifacts, cleanup, err := ParseArtifacts([]string{"darwin/arm64:" + arm64, "darwin/amd64:" + amd64}, ParseOptions{})
+	if err != nil {
+		t.Fatalf("ParseArtifacts: %v", err)
+	}
+	defer cleanup()
+
+	if got := artifacts[0].MinOS.String(); got != "14.2" {
+		t.Errorf("darwin/arm64 MinOS = %s, want 14.2", got)
+	}
+	if !artifacts[1].MinOS.IsZero() {
+		t.Errorf("darwin/amd64 MinOS = %s, want unset", artifacts[1].MinOS)
+	}
+}
+
+func TestArtifactSplit(t *testing.T) {
+	dir := t.TempDir()
+	path := writeBinary(t, dir, "mytool", fakeFatBinary(t,
+		fakeMachO(t, "amd64", versionMinCmd(t, 10, 13)),
+		fakeMachO(t, "arm64", buildVersionCmd(t, machoPlatformMacOS, 11, 0)),
+	))
+
+	artifacts, cleanup, err := ParseArtifacts([]string{"darwin/universal:" + path}, ParseOptions{})
+	if err != nil {
+		t.Fatalf("ParseArtifacts: %v", err)
+	}
+	defer cleanup()
+	if got := artifacts[0].MinOS.String(); got != "11.0" {
+		t.Errorf("universal MinOS = %s, want 11.0", got)
+	}
+
+	split := artifacts[0].Split()
+	if len(split) != 2 {
+		t.Fatalf("Split() returned %d artifacts, want 2", len(split))
+	}
+	for i, want := range []struct{ platform, suffix, minOS string }{
+		{"darwin/amd64", "darwin-x64", "10.13"},
+		{"darwin/arm64", "darwin-arm64", "11.0"},
+	} {
+		a := split[i]
+		if a.Platform.String() != want.platform || a.Mapping.Npm.PackageSuffix != want.suffix || a.MinOS.String() != want.minOS {
+			t.Errorf("Split()[%d] = %s %s %s, want %s %s %s", i, a.Platform, a.Mapping.Npm.PackageSuffix, a.MinOS, want.platform, want.suffix, want.minOS)
+		}
+		if a.Executables[0].Path != path {
+			t.Errorf("Split()[%d] should ship the universal binary, got %s", i, a.Executables[0].Path)
+		}
+	}
+
+	thin := Artifact{Platform: split[0].Platform}
+	if got := thin.Split(); len(got) != 1 || got[0].Platform != thin.Platform {
+		t.Errorf("Split() of a single-arch artifact = %v", got)
+	}
+}
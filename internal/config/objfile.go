@@ -0,0 +1,173 @@
+package config
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jacobarthurs/shipbin/internal/platforms"
+)
+
+const (
+	formatELF        = "ELF"
+	formatFreeBSDELF = "FreeBSD ELF"
+	formatMachO      = "Mach-O"
+	formatPE         = "PE"
+)
+
+var errNotObject = errors.New("not an ELF, Mach-O or PE binary")
+
+var objectFormats = map[string]string{
+	"linux":   formatELF,
+	"freebsd": formatFreeBSDELF,
+	"darwin":  formatMachO,
+	"windows": formatPE,
+}
+
+var elfMachines = map[string]elf.Machine{
+	"amd64":   elf.EM_X86_64,
+	"arm64":   elf.EM_AARCH64,
+	"386":     elf.EM_386,
+	"arm":     elf.EM_ARM,
+	"riscv64": elf.EM_RISCV,
+	"ppc64le": elf.EM_PPC64,
+	"s390x":   elf.EM_S390,
+}
+
+var machoCPUs = map[string]macho.Cpu{
+	"amd64": macho.CpuAmd64,
+	"arm64": macho.CpuArm64,
+}
+
+var peMachines = map[string]uint16{
+	"amd64": pe.IMAGE_FILE_MACHINE_AMD64,
+	"arm64": pe.IMAGE_FILE_MACHINE_ARM64,
+}
+
+type objectInfo struct {
+	format string
+	fat    bool
+	arches []string
+	interp string
+}
+
+func (o *objectInfo) String() string {
+	format := o.format
+	if o.fat {
+		format = "universal " + format
+	}
+	return fmt.Sprintf("%s %s", format, strings.Join(o.arches, "+"))
+}
+
+func checkArchitecture(path string, p platforms.Platform) error {
+	info, err := inspectObject(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+
+	want := &objectInfo{format: objectFormats[p.GOOS], fat: p.IsUniversal()}
+	match := info.format == want.format
+	for _, s := range p.Slices() {
+		want.arches = append(want.arches, s.GOARCH)
+		match = match && slices.Contains(info.arches, s.GOARCH)
+	}
+	if !match {
+		return fmt.Errorf("%s: found %s, expected %s for %s", filepath.Base(path), info, want, p)
+	}
+
+	musl := strings.Contains(info.interp, "ld-musl")
+	switch {
+	case musl && p.Libc != "musl":
+		return fmt.Errorf("%s: linked against musl (%s), declare it as %s", filepath.Base(path), info.interp, platforms.Platform{GOOS: p.GOOS, GOARCH: p.GOARCH, GOARM: p.GOARM, Libc: "musl"})
+	case p.Libc == "musl" && info.interp != "" && !musl:
+		return fmt.Errorf("%s: linked against glibc (%s), expected a musl or static binary for %s", filepath.Base(path), info.interp, p)
+	}
+	return nil
+}
+
+func inspectObject(path string) (*objectInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return nil, errNotObject
+	}
+
+	switch {
+	case bytes.Equal(magic, []byte(elf.ELFMAG)):
+		ef, err := elf.NewFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ELF header: %w", err)
+		}
+		info := &objectInfo{format: formatELF, arches: []string{archName(elfMachines, ef.Machine)}}
+		if ef.OSABI == elf.ELFOSABI_FREEBSD {
+			info.format = formatFreeBSDELF
+		}
+		if ef.Machine == elf.EM_PPC64 && ef.ByteOrder == binary.BigEndian {
+			info.arches = []string{"ppc64"}
+		}
+		for _, prog := range ef.Progs {
+			if prog.Type == elf.PT_INTERP {
+				data, err := io.ReadAll(prog.Open())
+				if err != nil {
+					return nil, fmt.Errorf("failed to read ELF interpreter: %w", err)
+				}
+				info.interp = strings.TrimRight(string(data), "\x00")
+			}
+		}
+		return info, nil
+
+	case bytes.HasPrefix(magic, []byte("MZ")):
+		pf, err := pe.NewFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PE header: %w", err)
+		}
+		return &objectInfo{format: formatPE, arches: []string{archName(peMachines, pf.Machine)}}, nil
+
+	case binary.BigEndian.Uint32(magic) == macho.MagicFat:
+		ff, err := macho.NewFatFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read universal Mach-O header: %w", err)
+		}
+		info := &objectInfo{format: formatMachO, fat: true}
+		for _, a := range ff.Arches {
+			info.arches = append(info.arches, archName(machoCPUs, a.Cpu))
+		}
+		return info, nil
+
+	case isMachOMagic(binary.LittleEndian.Uint32(magic)) || isMachOMagic(binary.BigEndian.Uint32(magic)):
+		mf, err := macho.NewFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Mach-O header: %w", err)
+		}
+		return &objectInfo{format: formatMachO, arches: []string{archName(machoCPUs, mf.Cpu)}}, nil
+	}
+
+	return nil, errNotObject
+}
+
+func isMachOMagic(m uint32) bool {
+	return m == macho.Magic32 || m == macho.Magic64
+}
+
+func archName[M comparable](table map[string]M, machine M) string {
+	for goarch, m := range table {
+		if m == machine {
+			return goarch
+		}
+	}
+	return fmt.Sprint(machine)
+}
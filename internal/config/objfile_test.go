@@ -0,0 +1,314 @@
+package config
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jacobarthurs/shipbin/internal/platforms"
+)
+
+func fakeBinary(t *testing.T, platform string) []byte {
+	t.Helper()
+	p, err := platforms.Parse(platform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goos, goarch := p.GOOS, p.GOARCH
+	if p.IsUniversal() {
+		return fakeUniversalBinary(t, "amd64", "arm64")
+	}
+
+	var buf bytes.Buffer
+	switch goos {
+	case "linux", "freebsd":
+		class, order := elf.ELFCLASS64, binary.ByteOrder(binary.LittleEndian)
+		switch goarch {
+		case "386", "arm":
+			class = elf.ELFCLASS32
+		case "s390x":
+			order = binary.BigEndian
+		}
+		var ident [elf.EI_NIDENT]byte
+		copy(ident[:], elf.ELFMAG)
+		ident[elf.EI_CLASS] = byte(class)
+		ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+		if order == binary.BigEndian {
+			ident[elf.EI_DATA] = byte(elf.ELFDATA2MSB)
+		}
+		ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+		if goos == "freebsd" {
+			ident[elf.EI_OSABI] = byte(elf.ELFOSABI_FREEBSD)
+		}
+		var hdr any = elf.Header64{Ident: ident, Type: uint16(elf.ET_EXEC), Machine: uint16(elfMachines[goarch]), Version: uint32(elf.EV_CURRENT), Ehsize: 64, Phentsize: 56, Shentsize: 64}
+		if class == elf.ELFCLASS32 {
+			hdr = elf.Header32{Ident: ident, Type: uint16(elf.ET_EXEC), Machine: uint16(elfMachines[goarch]), Version: uint32(elf.EV_CURRENT), Ehsize: 52, Phentsize: 32, Shentsize: 40}
+		}
+		if err := binary.Write(&buf, order, hdr); err != nil {
+			t.Fatal(err)
+		}
+	case "darwin":
+		mustWrite(t, &buf, macho.FileHeader{Magic: macho.Magic64, Cpu: machoCPUs[goarch], Type: macho.TypeExec})
+		mustWrite(t, &buf, uint32(0))
+	case "windows":
+		dos := make([]byte, 128)
+		copy(dos, "MZ")
+		binary.LittleEndian.PutUint32(dos[0x3c:], uint32(len(dos)))
+		buf.Write(dos)
+		buf.WriteString("PE\x00\x00")
+		mustWrite(t, &buf, pe.FileHeader{Machine: peMachines[goarch]})
+	default:
+		t.Fatalf("fakeBinary: unsupported platform %s", platform)
+	}
+	return buf.Bytes()
+}
+
+func fakeDynamicELF(t *testing.T, goarch, interp string) []byte {
+	t.Helper()
+	var ident [elf.EI_NIDENT]byte
+	copy(ident[:], elf.ELFMAG)
+	ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	var buf bytes.Buffer
+	mustWrite(t, &buf, elf.Header64{
+		Ident: ident, Type: uint16(elf.ET_EXEC), Machine: uint16(elfMachines[goarch]), Version: uint32(elf.EV_CURRENT),
+		Phoff: 64, Ehsize: 64, Phentsize: 56, Phnum: 1, Shentsize: 64,
+	})
+	size := uint64(len(interp) + 1)
+	mustWrite(t, &buf, elf.Prog64{Type: uint32(elf.PT_INTERP), Off: 64 + 56, Filesz: size, Memsz: size, Align: 1})
+	buf.WriteString(interp + "\x00")
+	return buf.Bytes()
+}
+
+func fakeUniversalBinary(t *testing.T, goarches ...string) []byte {
+	t.Helper()
+	var images [][]byte
+	for _, goarch := range goarches {
+		images = append(images, fakeBinary(t, "darwin/"+goarch))
+	}
+	return fakeFatBinary(t, images...)
+}
+
+func fakeFatBinary(t *testing.T, images ...[]byte) []byte {
+	t.Helper()
+	const align = 12
+	offset := uint32(1 << align)
+
+	var buf bytes.Buffer
+	mustWriteBE(t, &buf, uint32(macho.MagicFat))
+	mustWriteBE(t, &buf, uint32(len(images)))
+
+	for _, img := range images {
+		cpu := macho.Cpu(binary.LittleEndian.Uint32(img[4:]))
+		mustWriteBE(t, &buf, macho.FatArchHeader{Cpu: cpu, Offset: offset, Size: uint32(len(img)), Align: align})
+		offset += 1 << align
+	}
+	for _, img := range images {
+		buf.Write(make([]byte, (1<<align)-buf.Len()%(1<<align)))
+		buf.Write(img)
+	}
+	return buf.Bytes()
+}
+
+func mustWrite(t *testing.T, buf *bytes.Buffer, v any) {
+	t.Helper()
+	if err := binary.Write(buf, binary.LittleEndian, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustWriteBE(t *testing.T, buf *bytes.Buffer, v any) {
+	t.Helper()
+	if err := binary.Write(buf, binary.BigEndian, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeBinary(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInspectObject(t *testing.T) {
+	tests := []struct {
+		platform string
+		want     string
+	}{
+		{"linux/amd64", "ELF amd64"},
+		{"linux/arm64", "ELF arm64"},
+		{"darwin/amd64", "Mach-O amd64"},
+		{"darwin/arm64", "Mach-O arm64"},
+		{"windows/amd64", "PE amd64"},
+		{"windows/arm64", "PE arm64"},
+		{"linux/386", "ELF 386"},
+		{"linux/arm", "ELF arm"},
+		{"linux/riscv64", "ELF riscv64"},
+		{"linux/ppc64le", "ELF ppc64le"},
+		{"linux/s390x", "ELF s390x"},
+		{"freebsd/amd64", "FreeBSD ELF amd64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			path := writeBinary(t, t.TempDir(), "bin", fakeBinary(t, tt.platform))
+			info, err := inspectObject(path)
+			if err != nil {
+				t.Fatalf("inspectObject: %v", err)
+			}
+			if info.String() != tt.want {
+				t.Errorf("inspectObject() = %q, want %q", info, tt.want)
+			}
+		})
+	}
+}
+
+func TestInspectObject_Universal(t *testing.T) {
+	path := writeBinary(t, t.TempDir(), "bin", fakeUniversalBinary(t, "amd64", "arm64"))
+	info, err := inspectObject(path)
+	if err != nil {
+		t.Fatalf("inspectObject: %v", err)
+	}
+	if !info.fat {
+		t.Error("expected universal binary to be reported as fat")
+	}
+	if got, want := info.String(), "universal Mach-O amd64+arm64"; got != want {
+		t.Errorf("inspectObject() = %q, want %q", got, want)
+	}
+}
+
+func TestInspectObject_NotABinary(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"script": []byte("#!/bin/sh\necho hi\n"),
+		"short":  []byte("MZ"),
+		"empty":  nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := writeBinary(t, t.TempDir(), "bin", data)
+			if _, err := inspectObject(path); err == nil {
+				t.Fatal("expected error for non-binary file, got nil")
+			}
+		})
+	}
+}
+
+func TestCheckArchitecture(t *testing.T) {
+	dir := t.TempDir()
+	universal := writeBinary(t, dir, "universal", fakeUniversalBinary(t, "amd64", "arm64"))
+
+	tests := []struct {
+		name     string
+		data     []byte
+		platform string
+		wantErr  string
+	}{
+		{"match", fakeBinary(t, "linux/arm64"), "linux/arm64", ""},
+		{"wrong arch", fakeBinary(t, "linux/amd64"), "linux/arm64", "found ELF amd64, expected ELF arm64 for linux/arm64"},
+		{"wrong format", fakeBinary(t, "darwin/arm64"), "linux/arm64", "found Mach-O arm64, expected ELF arm64"},
+		{"pe for darwin", fakeBinary(t, "windows/amd64"), "darwin/amd64", "expected Mach-O amd64"},
+		{"linux for freebsd", fakeBinary(t, "linux/amd64"), "freebsd/amd64", "found ELF amd64, expected FreeBSD ELF amd64"},
+		{"arm variant", fakeBinary(t, "linux/arm"), "linux/arm/v6", ""},
+		{"universal", fakeUniversalBinary(t, "amd64", "arm64"), "darwin/universal", ""},
+		{"thin for universal", fakeBinary(t, "darwin/arm64"), "darwin/universal", "found Mach-O arm64, expected universal Mach-O amd64+arm64 for darwin/universal"},
+		{"missing slice", fakeUniversalBinary(t, "arm64"), "darwin/universal", "found universal Mach-O arm64, expected universal Mach-O amd64+arm64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeBinary(t, t.TempDir(), "mytool", tt.data)
+			p, err := platforms.Parse(tt.platform)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checkArchitecture(path, p)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			checkErrContains(t, err, "mytool", tt.wantErr)
+		})
+	}
+
+	for _, goarch := range []string{"amd64", "arm64"} {
+		if err := checkArchitecture(universal, platforms.Platform{GOOS: "darwin", GOARCH: goarch}); err != nil {
+			t.Errorf("universal binary should satisfy darwin/%s: %v", goarch, err)
+		}
+	}
+}
+
+func TestCheckArchitecture_Libc(t *testing.T) {
+	dir := t.TempDir()
+	glibc := writeBinary(t, dir, "mytool-gnu", fakeDynamicELF(t, "amd64", "/lib64/ld-linux-x86-64.so.2"))
+	musl := writeBinary(t, dir, "mytool-musl", fakeDynamicELF(t, "amd64", "/lib/ld-musl-x86_64.so.1"))
+	static := writeBinary(t, dir, "mytool-static", fakeBinary(t, "linux/amd64"))
+
+	linux := platforms.Platform{GOOS: "linux", GOARCH: "amd64"}
+	linuxMusl := platforms.Platform{GOOS: "linux", GOARCH: "amd64", Libc: "musl"}
+
+	for _, ok := range []struct {
+		path string
+		p    platforms.Platform
+	}{
+		{glibc, linux},
+		{musl, linuxMusl},
+		{static, linux},
+		{static, linuxMusl},
+	} {
+		if err := checkArchitecture(ok.path, ok.p); err != nil {
+			t.Errorf("checkArchitecture(%s, %s) unexpected error: %v", filepath.Base(ok.path), ok.p, err)
+		}
+	}
+
+	err := checkArchitecture(musl, linux)
+	if err == nil {
+		t.Fatal("expected error for musl binary declared as linux/amd64, got nil")
+	}
+	checkErrContains(t, err, "linked against musl", "linux-musl/amd64")
+
+	err = checkArchitecture(glibc, linuxMusl)
+	if err == nil {
+		t.Fatal("expected error for glibc binary declared as linux-musl/amd64, got nil")
+	}
+	checkErrContains(t, err, "linked against glibc", "/lib64/ld-linux-x86-64.so.2")
+}
+
+func TestParseArtifacts_ArchMismatch(t *testing.T) {
+	dir := t.TempDir()
+	amd64 := makeExe(t, dir, "mytool-amd64", "linux/amd64")
+	arm64 := makeExe(t, dir, "mytool-arm64", "linux/arm64")
+
+	_, _, err := ParseArtifacts([]string{
+		"linux/amd64:" + arm64,
+		"linux/arm64:" + amd64,
+	}, ParseOptions{})
+	if err == nil {
+		t.Fatal("expected error for swapped artifacts, got nil")
+	}
+	checkErrContains(t, err,
+		`--artifact "linux/amd64:`+arm64+`": mytool-arm64: found ELF arm64, expected ELF amd64`,
+		"mytool-amd64: found ELF amd64, expected ELF arm64",
+		"--skip-arch-check",
+	)
+
+	artifacts, cleanup, err := ParseArtifacts([]string{"linux/amd64:" + arm64}, ParseOptions{SkipArchCheck: true})
+	if err != nil {
+		t.Fatalf("SkipArchCheck should bypass the check: %v", err)
+	}
+	defer cleanup()
+	if len(artifacts) != 1 {
+		t.Errorf("expected 1 artifact, got %d", len(artifacts))
+	}
+}
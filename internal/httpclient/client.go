@@ -0,0 +1,178 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultRetries = 3
+	DefaultTimeout = 5 * time.Minute
+)
+
+var (
+	baseDelay     = time.Second
+	maxDelay      = 30 * time.Second
+	maxRetryAfter = 5 * time.Minute
+)
+
+// Client sends requests with http.DefaultClient, retrying transient failures:
+// network errors, 408, 429 and 5xx responses. Any other status, such as a 400
+// or 409 for a file that already exists, is returned as is. A nil Client
+// makes a single attempt without a timeout.
+type Client struct {
+	// Retries is how many times a failed request is sent again.
+	Retries int
+	// Timeout bounds each attempt, including reading the response body.
+	Timeout time.Duration
+	// OnRetry is called before each retry, unless the request's context
+	// carries its own callback from WithOnRetry.
+	OnRetry func(Retry)
+}
+
+type onRetryKey struct{}
+
+// WithOnRetry returns a copy of ctx whose requests report their retries to
+// onRetry, so that concurrent uploads can each report through their own
+// reporter.
+func WithOnRetry(ctx context.Context, onRetry func(Retry)) context.Context {
+	return context.WithValue(ctx, onRetryKey{}, onRetry)
+}
+
+// Retry describes a failed attempt that is about to be retried.
+type Retry struct {
+	Method  string
+	URL     string
+	Attempt int
+	Retries int
+	Wait    time.Duration
+	Reason  string
+}
+
+func (r Retry) String() string {
+	return fmt.Sprintf("%s %s %s, retrying in %s (retry %d of %d)", r.Method, r.URL, r.Reason, r.Wait.Round(100*time.Millisecond), r.Attempt, r.Retries)
+}
+
+func New(retries int, timeout time.Duration, onRetry func(Retry)) *Client {
+	return &Client{Retries: retries, Timeout: timeout, OnRetry: onRetry}
+}
+
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if c == nil {
+		return http.DefaultClient.Do(req)
+	}
+
+	ctx := req.Context()
+	onRetry := c.OnRetry
+	if f, ok := ctx.Value(onRetryKey{}).(func(Retry)); ok {
+		onRetry = f
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(req, attempt)
+		if attempt >= c.Retries || !retryable(resp, err) || ctx.Err() != nil || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		wait := backoff(attempt)
+		reason := ""
+		if err != nil {
+			reason = "failed: " + err.Error()
+		} else {
+			reason = "returned " + resp.Status
+			if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				wait = after
+			}
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			_ = resp.Body.Close()
+		}
+		if onRetry != nil {
+			onRetry(Retry{Method: req.Method, URL: req.URL.Redacted(), Attempt: attempt + 1, Retries: c.Retries, Wait: wait, Reason: reason})
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) attempt(req *http.Request, n int) (*http.Response, error) {
+	ctx, cancel := req.Context(), context.CancelFunc(func() {})
+	if c.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+	}
+
+	r := req.Clone(ctx)
+	if n > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		r.Body = body
+	}
+
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) && req.Context().Err() == nil {
+			err = fmt.Errorf("timed out after %s: %w", c.Timeout, err)
+		}
+		return nil, err
+	}
+	resp.Body = cancelOnClose{resp.Body, cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented
+}
+
+// backoff doubles the delay with every attempt and picks a random point in
+// its upper half, so that concurrent uploads don't retry in lockstep.
+func backoff(attempt int) time.Duration {
+	d := maxDelay
+	if attempt < 30 {
+		d = min(baseDelay<<attempt, maxDelay)
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func retryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	var d time.Duration
+	if secs, err := strconv.Atoi(v); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		d = time.Until(t)
+	} else {
+		return 0, false
+	}
+	return min(max(d, 0), maxRetryAfter), true
+}
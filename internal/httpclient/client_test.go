@@ -0,0 +1,254 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fastBackoff(t *testing.T) {
+	t.Helper()
+	old := baseDelay
+	baseDelay = time.Millisecond
+	t.Cleanup(func() { baseDelay = old })
+}
+
+func TestDo_RetriesTransientFailures(t *testing.T) {
+	fastBackoff(t)
+
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("attempt %d body = %q, want payload", attempts.Load()+1, body)
+		}
+		switch attempts.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	defer srv.Close()
+
+	var retries []Retry
+	c := New(3, time.Second, func(r Retry) { retries = append(retries, r) })
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodPost, srv.URL, strings.NewReader("payload"))
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "ok" {
+		t.Errorf("body = %q, want ok", body)
+	}
+
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("attempts = %d, want 3", n)
+	}
+	if len(retries) != 2 {
+		t.Fatalf("retries = %v, want 2", retries)
+	}
+	if r := retries[0]; r.Attempt != 1 || r.Retries != 3 || !strings.Contains(r.Reason, "502") {
+		t.Errorf("first retry = %+v", r)
+	}
+	if s := retries[1].String(); !strings.Contains(s, "POST") || !strings.Contains(s, "429") || !strings.Contains(s, "retry 2 of 3") {
+		t.Errorf("retry message = %q", s)
+	}
+}
+
+func TestDo_ContextOnRetry(t *testing.T) {
+	fastBackoff(t)
+
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	var retries int
+	c := New(1, time.Second, func(r Retry) { t.Errorf("the client's OnRetry was called: %s", r) })
+	ctx := WithOnRetry(t.Context(), func(Retry) { retries++ })
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	_ = resp.Body.Close()
+	if retries != 1 {
+		t.Errorf("retries = %d, want 1 reported to the context's callback", retries)
+	}
+}
+
+func TestDo_DoesNotRetryClientErrors(t *testing.T) {
+	fastBackoff(t)
+
+	for _, status := range []int{http.StatusBadRequest, http.StatusForbidden, http.StatusConflict} {
+		var attempts atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(status)
+		}))
+
+		c := New(3, time.Second, func(r Retry) { t.Errorf("unexpected retry: %s", r) })
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodPost, srv.URL, strings.NewReader("payload"))
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+		_ = resp.Body.Close()
+		srv.Close()
+
+		if resp.StatusCode != status {
+			t.Errorf("status = %d, want %d", resp.StatusCode, status)
+		}
+		if n := attempts.Load(); n != 1 {
+			t.Errorf("%d: attempts = %d, want 1", status, n)
+		}
+	}
+}
+
+func TestDo_GivesUp(t *testing.T) {
+	fastBackoff(t)
+
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+	resp, err := New(2, time.Second, nil).Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", resp.StatusCode)
+	}
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("attempts = %d, want 3", n)
+	}
+}
+
+func TestDo_RetryAfter(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	var wait time.Duration
+	c := New(1, time.Second, func(r Retry) { wait = r.Wait })
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+	start := time.Now()
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	if wait != time.Second {
+		t.Errorf("wait = %s, want the 1s from Retry-After", wait)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, before Retry-After", elapsed)
+	}
+}
+
+func TestDo_TimeoutPerAttempt(t *testing.T) {
+	fastBackoff(t)
+
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	var reason string
+	c := New(1, 100*time.Millisecond, func(r Retry) { reason = r.Reason })
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "ok" {
+		t.Errorf("body = %q, want ok", body)
+	}
+	if !strings.Contains(reason, "timed out after 100ms") {
+		t.Errorf("retry reason = %q, want a timeout", reason)
+	}
+}
+
+func TestDo_Canceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	c := New(3, time.Second, func(Retry) { cancel() })
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err := c.Do(req); err != context.Canceled {
+		t.Errorf("Do() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"-5", 0, true},
+		{"86400", maxRetryAfter, true},
+		{"soon", 0, false},
+		{"Mon, 02 Jan 2006 15:04:05 GMT", 0, true},
+	}
+	for _, tt := range tests {
+		got, ok := retryAfter(tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %s, %t, want %s, %t", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := range 10 {
+		d := min(baseDelay<<attempt, maxDelay)
+		for range 20 {
+			if got := backoff(attempt); got < d/2 || got > d {
+				t.Errorf("backoff(%d) = %s, want between %s and %s", attempt, got, d/2, d)
+			}
+		}
+	}
+	for _, attempt := range []int{34, 64, 1000} {
+		if got := backoff(attempt); got < maxDelay/2 || got > maxDelay {
+			t.Errorf("backoff(%d) = %s, want between %s and %s", attempt, got, maxDelay/2, maxDelay)
+		}
+	}
+}
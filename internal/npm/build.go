@@ -6,6 +6,10 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jacobarthurs/shipbin/internal/config"
 )
 
 type packageJSON struct {
@@ -15,9 +19,15 @@ type packageJSON struct {
 	License      string            `json:"license"`
 	OS           []string          `json:"os,omitempty"`
 	CPU          []string          `json:"cpu,omitempty"`
+	Libc         []string          `json:"libc,omitempty"`
 	Files        []string          `json:"files"`
 	Bin          map[string]string `json:"bin,omitempty"`
 	OptionalDeps map[string]string `json:"optionalDependencies,omitempty"`
+	Shipbin      *shipbinMeta      `json:"shipbin,omitempty"`
+}
+
+type shipbinMeta struct {
+	MinOS string `json:"minOS,omitempty"`
 }
 
 type builtPackage struct {
@@ -35,7 +45,7 @@ func buildPlatformPackages(cfg *Config) ([]builtPackage, func(), error) {
 		}
 	}
 
-	for _, a := range cfg.Artifacts {
+	for _, a := range npmArtifacts(cfg.Artifacts) {
 		pkgName := fmt.Sprintf("@%s/%s-%s", cfg.Org, cfg.Name, a.Mapping.Npm.PackageSuffix)
 
 		dir, err := os.MkdirTemp("", "shipbin-npm-*")
@@ -51,14 +61,35 @@ func buildPlatformPackages(cfg *Config) ([]builtPackage, func(), error) {
 			return nil, nil, fmt.Errorf("failed to create bin dir for %s: %w", pkgName, err)
 		}
 
-		binaryName := cfg.Name
-		if a.Platform.GOOS == "windows" {
-			binaryName += ".exe"
+		for _, exe := range a.Executables {
+			binaryName := exe.Name
+			if binaryName == "" {
+				binaryName = cfg.Name
+			}
+			if a.Platform.GOOS == "windows" {
+				binaryName += ".exe"
+			}
+			destBinary := filepath.Join(binDir, binaryName)
+			if err := copyFile(exe.Path, destBinary, 0755); err != nil {
+				cleanup()
+				return nil, nil, fmt.Errorf("failed to copy binary %s for %s: %w", binaryName, pkgName, err)
+			}
 		}
-		destBinary := filepath.Join(binDir, binaryName)
-		if err := copyFile(a.Path, destBinary, 0755); err != nil {
-			cleanup()
-			return nil, nil, fmt.Errorf("failed to copy binary for %s: %w", pkgName, err)
+
+		files := []string{"bin"}
+		for _, f := range a.Files {
+			dest := filepath.Join(dir, filepath.FromSlash(f.Dest))
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				cleanup()
+				return nil, nil, fmt.Errorf("failed to create directory for %s in %s: %w", f.Dest, pkgName, err)
+			}
+			if err := copyFile(f.Src, dest, fileMode(f.Src)); err != nil {
+				cleanup()
+				return nil, nil, fmt.Errorf("failed to copy %s for %s: %w", f.Src, pkgName, err)
+			}
+			if top, _, _ := strings.Cut(f.Dest, "/"); !slices.Contains(files, top) {
+				files = append(files, top)
+			}
 		}
 
 		pkg := packageJSON{
@@ -68,7 +99,11 @@ func buildPlatformPackages(cfg *Config) ([]builtPackage, func(), error) {
 			License:     cfg.License,
 			OS:          []string{a.Mapping.Npm.OS},
 			CPU:         []string{a.Mapping.Npm.CPU},
-			Files:       []string{"bin"},
+			Libc:        libcField(a, cfg.Artifacts),
+			Files:       files,
+		}
+		if !a.MinOS.IsZero() {
+			pkg.Shipbin = &shipbinMeta{MinOS: a.MinOS.String()}
 		}
 		if err := writeJSON(filepath.Join(dir, "package.json"), pkg); err != nil {
 			cleanup()
@@ -81,6 +116,31 @@ func buildPlatformPackages(cfg *Config) ([]builtPackage, func(), error) {
 	return packages, cleanup, nil
 }
 
+func npmArtifacts(artifacts []config.Artifact) []config.Artifact {
+	var result []config.Artifact
+	for _, a := range artifacts {
+		result = append(result, a.Split()...)
+	}
+	return result
+}
+
+func libcField(a config.Artifact, artifacts []config.Artifact) []string {
+	if a.Mapping.Npm.Libc != "" {
+		return []string{a.Mapping.Npm.Libc}
+	}
+	if a.Platform.GOOS != "linux" {
+		return nil
+	}
+	musl := a.Platform
+	musl.Libc = "musl"
+	for _, other := range artifacts {
+		if other.Platform == musl {
+			return []string{"glibc"}
+		}
+	}
+	return nil
+}
+
 func buildRootPackage(cfg *Config) (builtPackage, func(), error) {
 	rootName := cfg.Name
 
@@ -95,14 +155,18 @@ func buildRootPackage(cfg *Config) (builtPackage, func(), error) {
 		cleanup()
 		return builtPackage{}, nil, fmt.Errorf("failed to create bin dir for root package: %w", err)
 	}
-	wrapperPath := filepath.Join(binDir, cfg.Name)
-	if err := os.WriteFile(wrapperPath, []byte(wrapperScript(cfg.Name, cfg.Org)), 0755); err != nil {
-		cleanup()
-		return builtPackage{}, nil, fmt.Errorf("failed to write wrapper script: %w", err)
+	bin := make(map[string]string, len(cfg.executables()))
+	for _, exe := range cfg.executables() {
+		wrapperPath := filepath.Join(binDir, exe)
+		if err := os.WriteFile(wrapperPath, []byte(wrapperScript(cfg.Name, exe, cfg.Org)), 0755); err != nil {
+			cleanup()
+			return builtPackage{}, nil, fmt.Errorf("failed to write wrapper script for %s: %w", exe, err)
+		}
+		bin[exe] = fmt.Sprintf("bin/%s", exe)
 	}
 
 	optDeps := make(map[string]string, len(cfg.Artifacts))
-	for _, a := range cfg.Artifacts {
+	for _, a := range npmArtifacts(cfg.Artifacts) {
 		pkgName := fmt.Sprintf("@%s/%s-%s", cfg.Org, cfg.Name, a.Mapping.Npm.PackageSuffix)
 		optDeps[pkgName] = cfg.Version
 	}
@@ -113,7 +177,7 @@ func buildRootPackage(cfg *Config) (builtPackage, func(), error) {
 		Description:  cfg.Summary,
 		License:      cfg.License,
 		Files:        []string{"bin"},
-		Bin:          map[string]string{cfg.Name: fmt.Sprintf("bin/%s", cfg.Name)},
+		Bin:          bin,
 		OptionalDeps: optDeps,
 	}
 	if err := writeJSON(filepath.Join(dir, "package.json"), pkg); err != nil {
@@ -150,6 +214,14 @@ func copyFile(src, dst string, mode os.FileMode) error {
 	return out.Close()
 }
 
+func fileMode(path string) os.FileMode {
+	info, err := os.Stat(path)
+	if err == nil && info.Mode()&0111 != 0 {
+		return 0755
+	}
+	return 0644
+}
+
 func writeJSON(path string, v any) error {
 	data, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
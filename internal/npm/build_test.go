@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/jacobarthurs/shipbin/internal/config"
@@ -26,18 +27,22 @@ func makeTestBinary(t *testing.T, dir, name string) string {
 
 func makeArtifact(t *testing.T, dir, goos, goarch string) config.Artifact {
 	t.Helper()
-	m, err := platforms.Lookup(goos, goarch)
+	p, err := platforms.Parse(goos + "/" + goarch)
 	if err != nil {
-		t.Fatalf("platforms.Lookup(%q, %q): %v", goos, goarch, err)
+		t.Fatalf("platforms.Parse: %v", err)
+	}
+	m, err := platforms.Lookup(p)
+	if err != nil {
+		t.Fatalf("platforms.Lookup(%s): %v", p, err)
 	}
 	binName := "binary-" + goos + "-" + goarch
 	if goos == "windows" {
 		binName += ".exe"
 	}
 	return config.Artifact{
-		Platform: platforms.Platform{GOOS: goos, GOARCH: goarch},
-		Mapping:  m,
-		Path:     makeTestBinary(t, dir, binName),
+		Platform:    p,
+		Mapping:     m,
+		Executables: []config.Executable{{Path: makeTestBinary(t, dir, binName)}},
 	}
 }
 
@@ -122,6 +127,150 @@ func TestBuildPlatformPackages_MultipleArtifacts(t *testing.T) {
 	}
 }
 
+func TestBuildPlatformPackages_Libc(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{
+		Name:    "mytool",
+		Version: "1.0.0",
+		Org:     "myorg",
+		Artifacts: []config.Artifact{
+			makeArtifact(t, dir, "linux", "amd64"),
+			makeArtifact(t, dir, "linux-musl", "amd64"),
+			makeArtifact(t, dir, "linux", "arm64"),
+			makeArtifact(t, dir, "darwin", "arm64"),
+		},
+	}
+
+	pkgs, cleanup, err := buildPlatformPackages(cfg)
+	if err != nil {
+		t.Fatalf("buildPlatformPackages: %v", err)
+	}
+	defer cleanup()
+
+	want := map[string]string{
+		"@myorg/mytool-linux-x64":      "glibc",
+		"@myorg/mytool-linux-x64-musl": "musl",
+		"@myorg/mytool-linux-arm64":    "",
+		"@myorg/mytool-darwin-arm64":   "",
+	}
+	for _, pkg := range pkgs {
+		data, err := os.ReadFile(filepath.Join(pkg.dir, "package.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pj packageJSON
+		if err := json.Unmarshal(data, &pj); err != nil {
+			t.Fatal(err)
+		}
+		got := strings.Join(pj.Libc, ",")
+		if w, ok := want[pkg.name]; !ok {
+			t.Errorf("unexpected package %s", pkg.name)
+		} else if got != w {
+			t.Errorf("%s libc = %q, want %q", pkg.name, got, w)
+		}
+	}
+}
+
+func TestBuildPlatformPackages_MinOS(t *testing.T) {
+	dir := t.TempDir()
+	darwin := makeArtifact(t, dir, "darwin", "arm64")
+	darwin.MinOS = config.OSVersion{Major: 13, Minor: 0}
+	cfg := &Config{
+		Name:      "mytool",
+		Version:   "1.0.0",
+		Org:       "myorg",
+		Artifacts: []config.Artifact{darwin, makeArtifact(t, dir, "linux", "amd64")},
+	}
+
+	pkgs, cleanup, err := buildPlatformPackages(cfg)
+	if err != nil {
+		t.Fatalf("buildPlatformPackages: %v", err)
+	}
+	defer cleanup()
+
+	want := map[string]string{
+		"@myorg/mytool-darwin-arm64": "13.0",
+		"@myorg/mytool-linux-x64":    "",
+	}
+	for _, pkg := range pkgs {
+		data, err := os.ReadFile(filepath.Join(pkg.dir, "package.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pj packageJSON
+		if err := json.Unmarshal(data, &pj); err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		if pj.Shipbin != nil {
+			got = pj.Shipbin.MinOS
+		}
+		if got != want[pkg.name] {
+			t.Errorf("%s shipbin.minOS = %q, want %q", pkg.name, got, want[pkg.name])
+		}
+	}
+}
+
+func TestBuildPlatformPackages_Universal(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{
+		Name:      "mytool",
+		Version:   "1.0.0",
+		Org:       "myorg",
+		Artifacts: []config.Artifact{makeArtifact(t, dir, "darwin", "universal"), makeArtifact(t, dir, "linux", "amd64")},
+	}
+
+	pkgs, cleanup, err := buildPlatformPackages(cfg)
+	if err != nil {
+		t.Fatalf("buildPlatformPackages: %v", err)
+	}
+	defer cleanup()
+
+	want := map[string]string{
+		"@myorg/mytool-darwin-x64":   "x64",
+		"@myorg/mytool-darwin-arm64": "arm64",
+		"@myorg/mytool-linux-x64":    "x64",
+	}
+	if len(pkgs) != len(want) {
+		t.Fatalf("expected %d packages, got %d", len(want), len(pkgs))
+	}
+	for _, pkg := range pkgs {
+		data, err := os.ReadFile(filepath.Join(pkg.dir, "package.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var pj packageJSON
+		if err := json.Unmarshal(data, &pj); err != nil {
+			t.Fatal(err)
+		}
+		if cpu, ok := want[pkg.name]; !ok || len(pj.CPU) != 1 || pj.CPU[0] != cpu {
+			t.Errorf("%s cpu = %v, want [%s]", pkg.name, pj.CPU, cpu)
+		}
+		if _, err := os.Stat(filepath.Join(pkg.dir, "bin", "mytool")); err != nil {
+			t.Errorf("%s: binary not found: %v", pkg.name, err)
+		}
+	}
+
+	root, rootCleanup, err := buildRootPackage(cfg)
+	if err != nil {
+		t.Fatalf("buildRootPackage: %v", err)
+	}
+	defer rootCleanup()
+	data, err := os.ReadFile(filepath.Join(root.dir, "package.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pj packageJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		t.Fatal(err)
+	}
+	for name := range want {
+		if _, ok := pj.OptionalDeps[name]; !ok {
+			t.Errorf("root package missing optional dependency %s", name)
+		}
+	}
+}
+
 func TestBuildPlatformPackages_WindowsBinaryHasExeSuffix(t *testing.T) {
 	dir := t.TempDir()
 	a := makeArtifact(t, dir, "windows", "amd64")
@@ -173,6 +322,84 @@ func TestBuildPlatformPackages_CleanupRemovesDirs(t *testing.T) {
 	}
 }
 
+func TestBuildPlatformPackages_MultipleExecutables(t *testing.T) {
+	dir := t.TempDir()
+	a := makeArtifact(t, dir, "windows", "amd64")
+	a.Executables[0].Name = "mytool"
+	a.Executables = append(a.Executables, config.Executable{
+		Name: "mytool-lsp",
+		Path: makeTestBinary(t, dir, "lsp.exe"),
+	})
+
+	cfg := &Config{
+		Name:        "mytool",
+		Executables: []string{"mytool", "mytool-lsp"},
+		Version:     "1.0.0",
+		Org:         "myorg",
+		Artifacts:   []config.Artifact{a},
+	}
+
+	pkgs, cleanup, err := buildPlatformPackages(cfg)
+	if err != nil {
+		t.Fatalf("buildPlatformPackages: %v", err)
+	}
+	defer cleanup()
+
+	for _, name := range []string{"mytool.exe", "mytool-lsp.exe"} {
+		if _, err := os.Stat(filepath.Join(pkgs[0].dir, "bin", name)); err != nil {
+			t.Errorf("platform package missing bin/%s: %v", name, err)
+		}
+	}
+}
+
+func TestBuildPlatformPackages_ExtraFiles(t *testing.T) {
+	dir := t.TempDir()
+	a := makeArtifact(t, dir, "linux", "amd64")
+	license := filepath.Join(dir, "LICENSE")
+	if err := os.WriteFile(license, []byte("MIT"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a.Files = []config.ExtraFile{
+		{Src: license, Dest: "share/LICENSE"},
+		{Src: makeTestBinary(t, dir, "libfoo.so"), Dest: "bin/libfoo.so"},
+	}
+
+	cfg := &Config{Name: "mytool", Version: "1.0.0", Org: "myorg", Artifacts: []config.Artifact{a}}
+
+	pkgs, cleanup, err := buildPlatformPackages(cfg)
+	if err != nil {
+		t.Fatalf("buildPlatformPackages: %v", err)
+	}
+	defer cleanup()
+
+	for _, rel := range []string{"share/LICENSE", "bin/libfoo.so"} {
+		if _, err := os.Stat(filepath.Join(pkgs[0].dir, filepath.FromSlash(rel))); err != nil {
+			t.Errorf("platform package missing %s: %v", rel, err)
+		}
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filepath.Join(pkgs[0].dir, "share", "LICENSE"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0644 {
+			t.Errorf("LICENSE mode = %04o, want 0644", info.Mode().Perm())
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(pkgs[0].dir, "package.json"))
+	if err != nil {
+		t.Fatalf("failed to read package.json: %v", err)
+	}
+	var pj packageJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		t.Fatalf("package.json is not valid JSON: %v", err)
+	}
+	if strings.Join(pj.Files, ",") != "bin,share" {
+		t.Errorf("package.json files = %v, want [bin share]", pj.Files)
+	}
+}
+
 func TestBuildRootPackage(t *testing.T) {
 	dir := t.TempDir()
 	a := makeArtifact(t, dir, "linux", "amd64")
@@ -230,6 +457,51 @@ func TestBuildRootPackage(t *testing.T) {
 	}
 }
 
+func TestBuildRootPackage_MultipleExecutables(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &Config{
+		Name:        "mytool",
+		Executables: []string{"mytool", "mytool-lsp"},
+		Version:     "1.0.0",
+		Org:         "myorg",
+		Artifacts:   []config.Artifact{makeArtifact(t, dir, "linux", "amd64")},
+	}
+
+	pkg, cleanup, err := buildRootPackage(cfg)
+	if err != nil {
+		t.Fatalf("buildRootPackage: %v", err)
+	}
+	defer cleanup()
+
+	data, err := os.ReadFile(filepath.Join(pkg.dir, "package.json"))
+	if err != nil {
+		t.Fatalf("failed to read package.json: %v", err)
+	}
+	var pj packageJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		t.Fatalf("package.json invalid JSON: %v", err)
+	}
+	if len(pj.Bin) != 2 {
+		t.Errorf("expected 2 bin entries, got %v", pj.Bin)
+	}
+
+	for _, exe := range []string{"mytool", "mytool-lsp"} {
+		if pj.Bin[exe] != "bin/"+exe {
+			t.Errorf("bin[%s] = %q, want %q", exe, pj.Bin[exe], "bin/"+exe)
+		}
+		script, err := os.ReadFile(filepath.Join(pkg.dir, "bin", exe))
+		if err != nil {
+			t.Fatalf("wrapper for %s not written: %v", exe, err)
+		}
+		if !strings.Contains(string(script), `const BIN_NAME = "`+exe+`"`) {
+			t.Errorf("wrapper for %s does not run %s", exe, exe)
+		}
+		if !strings.Contains(string(script), `const PKG_NAME = "mytool"`) {
+			t.Errorf("wrapper for %s does not reference the mytool platform packages", exe)
+		}
+	}
+}
+
 func TestBuildRootPackage_WithReadme(t *testing.T) {
 	dir := t.TempDir()
 	a := makeArtifact(t, dir, "linux", "amd64")
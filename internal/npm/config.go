@@ -3,14 +3,26 @@ package npm
 import "github.com/jacobarthurs/shipbin/internal/config"
 
 type Config struct {
-	Name       string
-	Version    string
-	Summary    string
-	License    string
-	Artifacts  []config.Artifact
-	DryRun     bool
-	Org        string
-	Tag        string
-	Provenance bool
-	Readme     string
+	Name        string
+	Executables []string
+	Version     string
+	Summary     string
+	License     string
+	Artifacts   []config.Artifact
+	DryRun      bool
+	Concurrency int
+	Org         string
+	Tag         string
+	Provenance  bool
+	Readme      string
+	UseCLI      bool
+	Registry    string
+	Access      string
+}
+
+func (c *Config) executables() []string {
+	if len(c.Executables) == 0 {
+		return []string{c.Name}
+	}
+	return c.Executables
 }
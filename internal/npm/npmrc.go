@@ -0,0 +1,98 @@
+package npm
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var npmrcEnvRe = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+type npmrcValue struct {
+	value string
+	path  string
+}
+
+type npmrc map[string]npmrcValue
+
+func npmrcPaths() []string {
+	paths := []string{".npmrc"}
+	if p := os.Getenv("NPM_CONFIG_USERCONFIG"); p != "" {
+		paths = append(paths, p)
+	} else if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".npmrc"))
+	}
+	return paths
+}
+
+func loadNpmrc() (npmrc, error) {
+	rc := make(npmrc)
+	for _, path := range npmrcPaths() {
+		if err := rc.read(path); err != nil {
+			return nil, err
+		}
+	}
+	return rc, nil
+}
+
+func (rc npmrc) read(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if _, seen := rc[key]; seen {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		value = npmrcEnvRe.ReplaceAllStringFunc(value, func(m string) string {
+			return os.Getenv(npmrcEnvRe.FindStringSubmatch(m)[1])
+		})
+		rc[key] = npmrcValue{value: value, path: path}
+	}
+	return scanner.Err()
+}
+
+func (rc npmrc) registry(pkgName string) string {
+	if scope, _, ok := strings.Cut(pkgName, "/"); ok && strings.HasPrefix(scope, "@") {
+		if v := rc[scope+":registry"].value; v != "" {
+			return v
+		}
+	}
+	return rc["registry"].value
+}
+
+func (rc npmrc) token(registry string) (string, string) {
+	v := rc[registryKey(registry)+":_authToken"]
+	return v.value, v.path
+}
+
+func registryKey(registry string) string {
+	key := registry
+	if i := strings.Index(key, "//"); i >= 0 {
+		key = key[i:]
+	}
+	if !strings.HasSuffix(key, "/") {
+		key += "/"
+	}
+	return key
+}
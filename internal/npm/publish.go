@@ -1,11 +1,20 @@
 package npm
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/jacobarthurs/shipbin/internal/httpclient"
+	"github.com/jacobarthurs/shipbin/internal/publisher"
 )
 
 const (
@@ -13,65 +22,349 @@ const (
 	registryPollTimeout  = 2 * time.Minute
 )
 
-func Publish(cfg *Config) error {
-	verb := "publishing"
-	if cfg.DryRun {
-		verb = "would publish"
-	}
+var packageNameRe = regexp.MustCompile(`^(@[a-z0-9-~][a-z0-9-._~]*/)?[a-z0-9-~][a-z0-9-._~]*$`)
+
+type Publisher struct {
+	cfg       *Config
+	platforms []builtPackage
+	root      builtPackage
+	publish   func(ctx context.Context, pkg builtPackage, tgz tarball, report publisher.Reporter) error
+	regs      *registries
+	client    *httpclient.Client
+	report    publisher.Reporter
+	journal   *publisher.Journal
+	mu        sync.Mutex
+	published []string
+	skipped   []string
+}
+
+func NewPublisher(cfg *Config) *Publisher {
+	return &Publisher{cfg: cfg}
+}
+
+func (p *Publisher) Name() string {
+	return "npm"
+}
+
+func (p *Publisher) Build(_ context.Context, rel publisher.Release) (func(), error) {
+	cfg := p.cfg
+	cfg.Name = rel.Name
+	cfg.Executables = rel.Executables
+	cfg.Version = rel.Version
+	cfg.Summary = rel.Summary
+	cfg.License = rel.License
+	cfg.Readme = rel.Readme
+	cfg.Artifacts = rel.Artifacts
+	cfg.DryRun = rel.DryRun
+	cfg.Concurrency = rel.Concurrency
+	p.report = rel.Report
+	p.client = httpclient.New(rel.Retries, rel.Timeout, nil)
+	p.journal = rel.Journal
 
-	fmt.Printf("npm: %s version %s\n", verb, cfg.Version)
+	// The registry ignores build metadata, so 1.2.3+build.5 would be
+	// published, and looked up, as 1.2.3.
+	if base, meta, ok := strings.Cut(cfg.Version, "+"); ok {
+		p.report.Warnf("npm", "build metadata %q dropped, as npm ignores it: publishing version %s", meta, base)
+		cfg.Version = base
+	}
 
 	platforms, cleanup, err := buildPlatformPackages(cfg)
+	if err != nil {
+		return nil, err
+	}
+	root, rootCleanup, err := buildRootPackage(cfg)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	p.platforms, p.root = platforms, root
+	return func() {
+		rootCleanup()
+		cleanup()
+	}, nil
+}
+
+func (p *Publisher) Validate(_ context.Context) error {
+	if err := p.validateNames(); err != nil {
+		return err
+	}
+
+	publish, regs, err := uploader(p.cfg, p.client, p.report)
 	if err != nil {
 		return err
 	}
-	defer cleanup()
+	for _, pkg := range p.packages() {
+		if _, err := regs.forPackage(pkg.name); err != nil {
+			return err
+		}
+	}
+	p.publish, p.regs = publish, regs
+	return nil
+}
+
+func (p *Publisher) Publish(ctx context.Context) error {
+	cfg := p.cfg
+	verb := "publishing"
+	if cfg.DryRun {
+		verb = "would publish"
+	}
 
-	for _, pkg := range platforms {
-		fmt.Printf("npm: %s %s...\n", verb, pkg.name)
-		if err := npmPublish(pkg.dir, cfg.Tag, cfg.Provenance, cfg.DryRun); err != nil {
+	p.report.Infof("npm", "%s version %s", verb, cfg.Version)
+
+	err := publisher.Parallel(ctx, cfg.Concurrency, len(p.platforms), p.report, func(ctx context.Context, i int, report publisher.Reporter) error {
+		pkg := p.platforms[i]
+		if err := p.upload(report.ReportRetries(ctx, "npm"), pkg, report); err != nil {
 			return fmt.Errorf("npm: failed to publish %s: %w", pkg.name, err)
 		}
+		return nil
+	})
+	publisher.SortLike(p.published, p.Packages())
+	publisher.SortLike(p.skipped, p.Packages())
+	if err != nil {
+		return err
 	}
 
 	if !cfg.DryRun {
-		fmt.Println("npm: waiting for registry propagation...")
-		for _, pkg := range platforms {
-			if err := pollUntilVisible(pkg.name, cfg.Version); err != nil {
+		p.report.Infof("npm", "waiting for registry propagation...")
+		var uploaded []builtPackage
+		for _, pkg := range p.platforms {
+			if !slices.Contains(p.skipped, pkg.name) {
+				uploaded = append(uploaded, pkg)
+			}
+		}
+		err := publisher.Parallel(ctx, cfg.Concurrency, len(uploaded), p.report, func(ctx context.Context, i int, report publisher.Reporter) error {
+			pkg := uploaded[i]
+			reg, err := p.regs.forPackage(pkg.name)
+			if err != nil {
+				return err
+			}
+			if err := pollUntilVisible(report.ReportRetries(ctx, "npm"), reg, pkg.name, cfg.Version); err != nil {
 				return fmt.Errorf("npm: registry propagation timed out for %s: %w", pkg.name, err)
 			}
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 	}
 
-	root, rootCleanup, err := buildRootPackage(cfg)
+	if err := p.upload(p.report.ReportRetries(ctx, "npm"), p.root, p.report); err != nil {
+		return fmt.Errorf("npm: failed to publish root package %s: %w", p.root.name, err)
+	}
+
+	p.report.Infof("npm", "done")
+	return nil
+}
+
+func (p *Publisher) upload(ctx context.Context, pkg builtPackage, report publisher.Reporter) error {
+	tgz, err := packTarball(pkg.dir, pkg.name, p.cfg.Version)
 	if err != nil {
 		return err
 	}
-	defer rootCleanup()
-
-	fmt.Printf("npm: %s %s...\n", verb, root.name)
-	if err := npmPublish(root.dir, cfg.Tag, cfg.Provenance, cfg.DryRun); err != nil {
-		return fmt.Errorf("npm: failed to publish root package %s: %w", root.name, err)
+	done, err := p.alreadyPublished(ctx, pkg.name, tgz)
+	if err != nil {
+		return err
+	}
+	if done {
+		p.mu.Lock()
+		p.skipped = append(p.skipped, pkg.name)
+		p.mu.Unlock()
+		report.Skipped("npm", pkg.name)
+		return nil
 	}
 
-	fmt.Println("npm: done")
+	report.Publishing("npm", pkg.name, p.cfg.DryRun)
+	if err := p.publish(ctx, pkg, tgz, report); err != nil {
+		// A conflict may come from retrying an upload that went through
+		// before its response was lost.
+		if !errors.Is(err, errPublishConflict) {
+			return err
+		}
+		if done, _ := p.alreadyPublished(ctx, pkg.name, tgz); !done {
+			return err
+		}
+	}
+	if !p.cfg.DryRun {
+		p.mu.Lock()
+		p.published = append(p.published, pkg.name)
+		p.mu.Unlock()
+		report.Published("npm", pkg.name)
+		if err := p.journal.Record("npm", pkg.name, tgz.integrity); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func npmPublish(dir, tag string, provenance, dryRun bool) error {
-	args := []string{"publish", "--access", "public", "--tag", tag}
+func (p *Publisher) alreadyPublished(ctx context.Context, name string, tgz tarball) (bool, error) {
+	if p.journal.Digest("npm", name) == tgz.integrity {
+		return true, nil
+	}
+	if p.cfg.DryRun {
+		return false, nil
+	}
+
+	reg, err := p.regs.forPackage(name)
+	if err != nil {
+		return false, err
+	}
+	d, err := reg.publishedDist(ctx, name, p.cfg.Version)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up %s@%s: %w", name, p.cfg.Version, err)
+	}
+	if d == nil {
+		return false, nil
+	}
+	if d.Integrity == tgz.integrity {
+		return true, nil
+	}
+
+	published, err := reg.download(ctx, d.Tarball)
+	if err != nil {
+		return false, fmt.Errorf("failed to download the published %s@%s: %w", name, p.cfg.Version, err)
+	}
+	same, err := sameContents(published, tgz.data)
+	if err != nil {
+		return false, fmt.Errorf("failed to compare with the published %s@%s: %w", name, p.cfg.Version, err)
+	}
+	if !same {
+		return false, fmt.Errorf("version %s is already published with different contents: publish a new version instead", p.cfg.Version)
+	}
+	return true, nil
+}
+
+func (p *Publisher) Exists(ctx context.Context) ([]string, error) {
+	ctx = p.report.ReportRetries(ctx, "npm")
+	regs := p.regs
+	if regs == nil {
+		var err error
+		if regs, err = newRegistries(p.cfg.Registry, false, p.client, p.report); err != nil {
+			return nil, err
+		}
+	}
+
+	var existing []string
+	for _, pkg := range p.packages() {
+		reg, err := regs.forPackage(pkg.name)
+		if err != nil {
+			return nil, err
+		}
+		found, err := reg.hasVersion(ctx, pkg.name, p.cfg.Version)
+		if err != nil {
+			return nil, fmt.Errorf("npm: failed to look up %s@%s: %w", pkg.name, p.cfg.Version, err)
+		}
+		if found {
+			existing = append(existing, pkg.name)
+		}
+	}
+	return existing, nil
+}
+
+func (p *Publisher) Pack(ctx context.Context, dir string) ([]publisher.PackedFile, error) {
+	if err := p.validateNames(); err != nil {
+		return nil, err
+	}
+
+	var files []publisher.PackedFile
+	for _, pkg := range p.packages() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		tgz, err := packTarball(pkg.dir, pkg.name, p.cfg.Version)
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(dir, packFilename(pkg.name, p.cfg.Version))
+		if err := os.WriteFile(path, tgz.data, 0644); err != nil {
+			return nil, fmt.Errorf("npm: failed to write %s: %w", path, err)
+		}
+		p.report.Infof("npm", "wrote %s", path)
+		files = append(files, publisher.PackedFile{Package: pkg.name, Path: path})
+	}
+	return files, nil
+}
+
+func (p *Publisher) Packages() []string {
+	var names []string
+	for _, pkg := range p.packages() {
+		names = append(names, pkg.name)
+	}
+	return names
+}
+
+func (p *Publisher) Published() []string {
+	return p.published
+}
+
+func (p *Publisher) Skipped() []string {
+	return p.skipped
+}
+
+func (p *Publisher) packages() []builtPackage {
+	return append(slices.Clone(p.platforms), p.root)
+}
+
+func (p *Publisher) validateNames() error {
+	var errs []error
+	for _, pkg := range p.packages() {
+		if len(pkg.name) > 214 || !packageNameRe.MatchString(pkg.name) {
+			errs = append(errs, fmt.Errorf("npm: invalid package name %q: names must be lowercase, URL-safe and at most 214 characters", pkg.name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// packFilename follows npm pack: @scope/name becomes scope-name-version.tgz.
+func packFilename(name, version string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(name, "@"), "/", "-") + "-" + version + ".tgz"
+}
+
+func uploader(cfg *Config, client *httpclient.Client, report publisher.Reporter) (func(ctx context.Context, pkg builtPackage, tgz tarball, report publisher.Reporter) error, *registries, error) {
+	useCLI := cfg.UseCLI
+	if cfg.Provenance && !useCLI {
+		if _, err := exec.LookPath("npm"); err != nil && !cfg.DryRun {
+			return nil, nil, fmt.Errorf("npm: provenance attestations are generated by the npm CLI, which was not found: install Node.js or pass --provenance=false")
+		}
+		useCLI = true
+	}
+
+	regs, err := newRegistries(cfg.Registry, !useCLI && !cfg.DryRun, client, report)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return func(ctx context.Context, pkg builtPackage, tgz tarball, report publisher.Reporter) error {
+		reg, err := regs.forPackage(pkg.name)
+		if err != nil {
+			return err
+		}
+		if useCLI {
+			return npmPublish(ctx, pkg, reg, cfg.Tag, cfg.Access, cfg.Provenance, cfg.DryRun, report)
+		}
+		return registryPublish(ctx, reg, pkg.dir, tgz, cfg.Tag, cfg.Access, cfg.DryRun, report)
+	}, regs, nil
+}
+
+func npmPublish(ctx context.Context, pkg builtPackage, reg registry, tag, access string, provenance, dryRun bool, report publisher.Reporter) error {
+	args := []string{"publish", "--access", access, "--tag", tag, "--registry", reg.url}
+	if scope, _, ok := strings.Cut(pkg.name, "/"); ok && strings.HasPrefix(scope, "@") {
+		args = append(args, "--"+scope+":registry="+reg.url)
+	}
 	if provenance {
 		args = append(args, "--provenance")
 	}
 	if dryRun {
-		fmt.Printf("npm: [dry run] npm %s (in %s)\n", strings.Join(args, " "), dir)
+		report.Infof("npm", "[dry run] npm %s (in %s)", strings.Join(args, " "), pkg.dir)
 		return nil
 	}
-	cmd := exec.Command("npm", args...)
-	cmd.Dir = dir
+	cmd := exec.CommandContext(ctx, "npm", args...)
+	cmd.Dir = pkg.dir
 	out, err := cmd.CombinedOutput()
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil {
-		return fmt.Errorf("%s", npmError(out))
+		return publishError(npmError(out))
 	}
 	return nil
 }
@@ -83,39 +376,56 @@ func npmError(out []byte) string {
 		if !strings.HasPrefix(line, "npm ERR! code ") {
 			continue
 		}
-		switch strings.TrimPrefix(line, "npm ERR! code ") {
-		case "EOTP":
-			return "2FA is blocking publish: generate and use a token with 2FA disabled"
-		case "ENEEDAUTH", "E401":
-			return "not authenticated: generate a token with npm and ensure it's configured correctly"
-		case "E403":
-			return "permission denied: ensure the token has write access to this package or org"
-		case "E409", "EPUBLISHCONFLICT":
-			return "version already exists: this version has already been published"
-		case "ENOTFOUND", "ETIMEDOUT", "ECONNREFUSED":
-			return "network error: unable to reach the npm registry, check your connection"
-		case "EUSAGE":
-			if strings.Contains(outStr, "provenance") {
-				return "provenance is not supported outside of CI: use --provenance=false when publishing locally"
-			}
+		if msg := npmErrorMessage(strings.TrimPrefix(line, "npm ERR! code "), outStr); msg != "" {
+			return msg
 		}
 	}
 	return "publish failed: " + strings.TrimSpace(outStr)
 }
 
-func pollUntilVisible(pkgName, version string) error {
-	url := fmt.Sprintf("https://registry.npmjs.org/%s/%s", pkgName, version)
+var errPublishConflict = errors.New("version already exists: this version has already been published")
+
+// publishError keeps a conflict recognizable, so that upload can check
+// whether the version was published by an earlier attempt.
+func publishError(msg string) error {
+	if msg == errPublishConflict.Error() {
+		return errPublishConflict
+	}
+	return errors.New(msg)
+}
+
+func npmErrorMessage(code, detail string) string {
+	switch code {
+	case "EOTP":
+		return "2FA is blocking publish: generate and use a token with 2FA disabled"
+	case "ENEEDAUTH", "E401":
+		return "not authenticated: generate a token with npm and ensure it's configured correctly"
+	case "E403":
+		return "permission denied: ensure the token has write access to this package or org"
+	case "E409", "EPUBLISHCONFLICT":
+		return errPublishConflict.Error()
+	case "ENOTFOUND", "ETIMEDOUT", "ECONNREFUSED":
+		return "network error: unable to reach the npm registry, check your connection"
+	case "EUSAGE":
+		if strings.Contains(detail, "provenance") {
+			return "provenance is not supported outside of CI: use --provenance=false when publishing locally"
+		}
+	}
+	return ""
+}
+
+func pollUntilVisible(ctx context.Context, reg registry, pkgName, version string) error {
 	deadline := time.Now().Add(registryPollTimeout)
 
 	for time.Now().Before(deadline) {
-		resp, err := http.Get(url)
-		if err == nil {
-			_ = resp.Body.Close()
-			if resp.StatusCode == http.StatusOK {
-				return nil
-			}
+		if found, err := reg.hasVersion(ctx, pkgName, version); err == nil && found {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(registryPollInterval):
 		}
-		time.Sleep(registryPollInterval)
 	}
 
 	return fmt.Errorf("package %s@%s not visible after %s", pkgName, version, registryPollTimeout)
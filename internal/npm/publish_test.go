@@ -1,8 +1,20 @@
 package npm
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+
+	"github.com/jacobarthurs/shipbin/internal/config"
+	"github.com/jacobarthurs/shipbin/internal/publisher"
 )
 
 func TestNpmError(t *testing.T) {
@@ -87,3 +99,347 @@ func TestNpmError(t *testing.T) {
 		})
 	}
 }
+
+func TestReleaseValidate(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("NPM_CONFIG_USERCONFIG", "missing")
+	t.Setenv("NODE_AUTH_TOKEN", "")
+
+	dir := t.TempDir()
+	p := NewPublisher(&Config{Org: "myorg", Tag: "latest", Access: "public"})
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:      "mytool",
+		Version:   "1.0.0",
+		Artifacts: []config.Artifact{makeArtifact(t, dir, "linux", "amd64"), makeArtifact(t, dir, "darwin", "arm64")},
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	want := []string{"@myorg/mytool-linux-x64", "@myorg/mytool-darwin-arm64", "mytool"}
+	if got := p.Packages(); !slices.Equal(got, want) {
+		t.Errorf("Packages() = %v, want %v", got, want)
+	}
+	if err := p.Validate(t.Context()); err == nil || !strings.Contains(err.Error(), "no credentials found") {
+		t.Errorf("Validate() error = %v, want missing credentials", err)
+	}
+
+	t.Setenv("NODE_AUTH_TOKEN", "secret")
+	if err := p.Validate(t.Context()); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+}
+
+func TestPublisherPack(t *testing.T) {
+	p := NewPublisher(&Config{Org: "myorg", Tag: "latest", Access: "public"})
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:      "mytool",
+		Version:   "1.0.0",
+		Artifacts: []config.Artifact{makeArtifact(t, t.TempDir(), "linux", "amd64")},
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	out := t.TempDir()
+	files, err := p.Pack(t.Context(), out)
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	want := []publisher.PackedFile{
+		{Package: "@myorg/mytool-linux-x64", Path: filepath.Join(out, "myorg-mytool-linux-x64-1.0.0.tgz")},
+		{Package: "mytool", Path: filepath.Join(out, "mytool-1.0.0.tgz")},
+	}
+	if !slices.Equal(files, want) {
+		t.Fatalf("Pack() = %v, want %v", files, want)
+	}
+	for _, f := range files {
+		if _, err := os.Stat(f.Path); err != nil {
+			t.Errorf("%s not written: %v", f.Path, err)
+		}
+	}
+}
+
+func TestPublisher_DropsBuildMetadata(t *testing.T) {
+	var warnings []string
+	p := NewPublisher(&Config{Org: "myorg"})
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:      "mytool",
+		Version:   "1.0.0+build.5",
+		Artifacts: []config.Artifact{makeArtifact(t, t.TempDir(), "linux", "amd64")},
+		Report: func(e publisher.Event) {
+			if e.Kind == publisher.EventWarning {
+				warnings = append(warnings, e.Message)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	files, err := p.Pack(t.Context(), t.TempDir())
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	for _, f := range files {
+		if !strings.HasSuffix(f.Path, "-1.0.0.tgz") {
+			t.Errorf("packed %s, want version 1.0.0", f.Path)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "build.5") {
+		t.Errorf("warnings = %q, want the dropped build metadata", warnings)
+	}
+}
+
+func TestReleaseValidate_InvalidNames(t *testing.T) {
+	p := NewPublisher(&Config{Org: "myorg"})
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:      "MyTool",
+		Version:   "1.0.0",
+		Artifacts: []config.Artifact{makeArtifact(t, t.TempDir(), "linux", "amd64")},
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	err = p.Validate(t.Context())
+	if err == nil {
+		t.Fatal("Validate() = nil, want invalid package names")
+	}
+	for _, name := range []string{"@myorg/MyTool-linux-x64", `"MyTool"`} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("Validate() error = %v, want it to mention %s", err, name)
+		}
+	}
+}
+
+func TestPublisherExists(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("NPM_CONFIG_USERCONFIG", "missing")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/@myorg/mytool-linux-x64/1.0.0" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	p := NewPublisher(&Config{Org: "myorg", Registry: srv.URL})
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:      "mytool",
+		Version:   "1.0.0",
+		Artifacts: []config.Artifact{makeArtifact(t, t.TempDir(), "linux", "amd64"), makeArtifact(t, t.TempDir(), "linux", "arm64")},
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	got, err := p.Exists(t.Context())
+	if err != nil {
+		t.Fatalf("Exists: %v", err)
+	}
+	if want := []string{"@myorg/mytool-linux-x64"}; !slices.Equal(got, want) {
+		t.Errorf("Exists() = %v, want %v", got, want)
+	}
+}
+
+func TestPublisherResume(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("NPM_CONFIG_USERCONFIG", "missing")
+	t.Setenv("NODE_AUTH_TOKEN", "secret")
+
+	p := NewPublisher(&Config{Org: "myorg", Tag: "latest", Access: "public"})
+	journal, err := publisher.OpenJournal(t.TempDir(), "mytool", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:        "mytool",
+		Version:     "1.0.0",
+		Artifacts:   []config.Artifact{makeArtifact(t, t.TempDir(), "linux", "amd64"), makeArtifact(t, t.TempDir(), "linux", "arm64"), makeArtifact(t, t.TempDir(), "darwin", "arm64")},
+		Journal:     journal,
+		Concurrency: 3,
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	x64, err := packTarball(p.platforms[0].dir, p.platforms[0].name, "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	arm64, err := packTarball(p.platforms[1].dir, p.platforms[1].name, "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Record("npm", p.platforms[1].name, arm64.integrity); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	published := map[string]bool{}
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPut:
+			name, _ := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/"))
+			published[name] = true
+		case r.URL.Path == "/@myorg/mytool-linux-x64/1.0.0":
+			_, _ = fmt.Fprintf(w, `{"dist":{"integrity":%q,"tarball":"%s/x64.tgz"}}`, x64.integrity, srv.URL)
+		case r.URL.Path == "/@myorg/mytool-linux-arm64/1.0.0":
+			t.Error("a package recorded in the journal was looked up in the registry")
+			http.NotFound(w, r)
+		case published[strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/1.0.0")]:
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	p.cfg.Registry = srv.URL
+
+	if err := p.Validate(t.Context()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if err := p.Publish(t.Context()); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	if want := []string{"@myorg/mytool-darwin-arm64", "mytool"}; !slices.Equal(p.Published(), want) {
+		t.Errorf("Published() = %v, want %v", p.Published(), want)
+	}
+	if want := []string{"@myorg/mytool-linux-x64", "@myorg/mytool-linux-arm64"}; !slices.Equal(p.Skipped(), want) {
+		t.Errorf("Skipped() = %v, want %v", p.Skipped(), want)
+	}
+	if journal.Digest("npm", "mytool") == "" || journal.Digest("npm", "@myorg/mytool-darwin-arm64") == "" {
+		t.Error("published packages should be recorded in the journal")
+	}
+}
+
+func TestPublisherResume_DifferentContents(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("NPM_CONFIG_USERCONFIG", "missing")
+	t.Setenv("NODE_AUTH_TOKEN", "secret")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name":"@myorg/mytool-linux-x64","version":"1.0.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	other, err := packTarball(dir, "@myorg/mytool-linux-x64", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/@myorg/mytool-linux-x64/1.0.0":
+			_, _ = fmt.Fprintf(w, `{"dist":{"integrity":%q,"tarball":"%s/x64.tgz"}}`, other.integrity, srv.URL)
+		case "/x64.tgz":
+			_, _ = w.Write(other.data)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewPublisher(&Config{Org: "myorg", Tag: "latest", Access: "public", Registry: srv.URL})
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:      "mytool",
+		Version:   "1.0.0",
+		Artifacts: []config.Artifact{makeArtifact(t, t.TempDir(), "linux", "amd64")},
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	if err := p.Validate(t.Context()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	err = p.Publish(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "different contents") {
+		t.Fatalf("Publish() error = %v, want different contents", err)
+	}
+	if len(p.Published()) != 0 {
+		t.Errorf("Published() = %v, want nothing", p.Published())
+	}
+}
+
+func TestPublisher_ConflictAfterRetry(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("NPM_CONFIG_USERCONFIG", "missing")
+	t.Setenv("NODE_AUTH_TOKEN", "secret")
+
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	integrity := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodGet {
+			name, _ := url.PathUnescape(strings.TrimSuffix(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/1.0.0"))
+			if integrity[name] == "" {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"dist":{"integrity":%q}}`, integrity[name])
+			return
+		}
+
+		name, _ := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/"))
+		if attempts[name]++; attempts[name] > 1 {
+			http.Error(w, `{"error":"You cannot publish over the previously published versions: 1.0.0."}`, http.StatusForbidden)
+			return
+		}
+		var doc struct {
+			Versions map[string]struct {
+				Dist struct {
+					Integrity string `json:"integrity"`
+				} `json:"dist"`
+			} `json:"versions"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+			t.Errorf("body is not JSON: %v", err)
+		}
+		integrity[name] = doc.Versions["1.0.0"].Dist.Integrity
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	p := NewPublisher(&Config{Org: "myorg", Tag: "latest", Access: "public", Registry: srv.URL})
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:      "mytool",
+		Version:   "1.0.0",
+		Artifacts: []config.Artifact{makeArtifact(t, t.TempDir(), "linux", "amd64")},
+		Retries:   1,
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	if err := p.Validate(t.Context()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if err := p.Publish(t.Context()); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if want := []string{"@myorg/mytool-linux-x64", "mytool"}; !slices.Equal(p.Published(), want) {
+		t.Errorf("Published() = %v, want %v", p.Published(), want)
+	}
+	if attempts["mytool"] != 2 {
+		t.Errorf("attempts = %d, want 2", attempts["mytool"])
+	}
+}
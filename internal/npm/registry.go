@@ -0,0 +1,245 @@
+package npm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jacobarthurs/shipbin/internal/httpclient"
+	"github.com/jacobarthurs/shipbin/internal/publisher"
+)
+
+var npmRegistryURL = "https://registry.npmjs.org/"
+
+type registry struct {
+	url    string
+	token  string
+	client *httpclient.Client
+}
+
+func (r registry) packageURL(name string) string {
+	return strings.TrimSuffix(r.url, "/") + "/" + url.PathEscape(name)
+}
+
+func (r registry) get(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	return r.client.Do(req)
+}
+
+func (r registry) versionURL(name, version string) string {
+	return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(r.url, "/"), url.PathEscape(name), url.PathEscape(version))
+}
+
+func (r registry) hasVersion(ctx context.Context, name, version string) (bool, error) {
+	resp, err := r.get(ctx, r.versionURL(name, version))
+	if err != nil {
+		return false, err
+	}
+	_ = resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("registry returned %d", resp.StatusCode)
+}
+
+type dist struct {
+	Integrity string `json:"integrity"`
+	Tarball   string `json:"tarball"`
+}
+
+func (r registry) publishedDist(ctx context.Context, name, version string) (*dist, error) {
+	resp, err := r.get(ctx, r.versionURL(name, version))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("registry returned %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Dist dist `json:"dist"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid version document: %w", err)
+	}
+	return &doc.Dist, nil
+}
+
+func (r registry) download(ctx context.Context, u string) ([]byte, error) {
+	resp, err := r.get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry returned %d for %s", resp.StatusCode, u)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+type registries struct {
+	override string
+	rc       npmrc
+	needAuth bool
+	client   *httpclient.Client
+	report   publisher.Reporter
+	mu       sync.Mutex
+	resolved map[string]registry
+}
+
+func newRegistries(override string, needAuth bool, client *httpclient.Client, report publisher.Reporter) (*registries, error) {
+	rc, err := loadNpmrc()
+	if err != nil {
+		return nil, fmt.Errorf("npm: failed to read .npmrc: %w", err)
+	}
+	return &registries{override: override, rc: rc, needAuth: needAuth, client: client, report: report, resolved: make(map[string]registry)}, nil
+}
+
+func (r *registries) forPackage(name string) (registry, error) {
+	u := r.override
+	if u == "" {
+		u = r.rc.registry(name)
+	}
+	if u == "" {
+		u = npmRegistryURL
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if reg, ok := r.resolved[u]; ok {
+		return reg, nil
+	}
+
+	reg := registry{url: u, client: r.client}
+	source := ""
+	if token := os.Getenv("NODE_AUTH_TOKEN"); token != "" {
+		reg.token, source = token, "NODE_AUTH_TOKEN"
+	} else if token, path := r.rc.token(u); token != "" {
+		reg.token, source = token, "the token from "+path
+	}
+	if r.needAuth {
+		if reg.token == "" {
+			return registry{}, fmt.Errorf(
+				"npm: no credentials found for %s\n"+
+					"set NODE_AUTH_TOKEN, or add %s:_authToken to your .npmrc",
+				u, registryKey(u),
+			)
+		}
+		r.report.Infof("npm", "authenticating to %s with %s", u, source)
+	}
+	r.resolved[u] = reg
+	return reg, nil
+}
+
+func registryPublish(ctx context.Context, reg registry, dir string, tgz tarball, tag, access string, dryRun bool, report publisher.Reporter) error {
+	var manifest map[string]any
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("invalid package.json: %w", err)
+	}
+	name, _ := manifest["name"].(string)
+	version, _ := manifest["version"].(string)
+
+	docURL := reg.packageURL(name)
+	if dryRun {
+		report.Infof("npm", "[dry run] PUT %s (%s, %d bytes, %s, access %s)", docURL, tgz.filename, len(tgz.data), tgz.integrity, access)
+		return nil
+	}
+
+	manifest["_id"] = name + "@" + version
+	manifest["dist"] = map[string]string{
+		"shasum":    tgz.shasum,
+		"integrity": tgz.integrity,
+		"tarball":   fmt.Sprintf("%s/-/%s", docURL, tgz.filename),
+	}
+	body, err := json.Marshal(map[string]any{
+		"_id":         name,
+		"name":        name,
+		"description": manifest["description"],
+		"access":      access,
+		"dist-tags":   map[string]string{tag: version},
+		"versions":    map[string]any{version: manifest},
+		"_attachments": map[string]any{
+			tgz.filename: map[string]any{
+				"content_type": "application/octet-stream",
+				"data":         base64.StdEncoding.EncodeToString(tgz.data),
+				"length":       len(tgz.data),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, docURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+reg.token)
+
+	resp, err := reg.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", npmErrorMessage("ECONNREFUSED", ""), err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return publishError(registryError(resp))
+	}
+	return nil
+}
+
+func registryError(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	detail := strings.TrimSpace(string(body))
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Error != "" {
+		detail = payload.Error
+	}
+
+	code := fmt.Sprintf("E%d", resp.StatusCode)
+	lower := strings.ToLower(detail)
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized && (strings.Contains(strings.ToLower(resp.Header.Get("WWW-Authenticate")), "otp") || strings.Contains(lower, "one-time pass")):
+		code = "EOTP"
+	case resp.StatusCode == http.StatusForbidden && strings.Contains(lower, "previously published"):
+		code = "EPUBLISHCONFLICT"
+	}
+
+	if msg := npmErrorMessage(code, detail); msg != "" {
+		return msg
+	}
+	return fmt.Sprintf("publish failed: registry returned %d: %s", resp.StatusCode, detail)
+}
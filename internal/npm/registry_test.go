@@ -0,0 +1,361 @@
+package npm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jacobarthurs/shipbin/internal/httpclient"
+)
+
+func writePackage(t *testing.T, name, version string) string {
+	t.Helper()
+	dir := t.TempDir()
+	pkg := packageJSON{Name: name, Version: version, Description: "mytool binary", Files: []string{"bin"}}
+	if err := writeJSON(filepath.Join(dir, "package.json"), pkg); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func packPackage(t *testing.T, dir, name string) tarball {
+	t.Helper()
+	tgz, err := packTarball(dir, name, "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tgz
+}
+
+func TestRegistryPublish(t *testing.T) {
+	var gotPath, gotAuth string
+	var doc map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("method = %s, want PUT", r.Method)
+		}
+		gotPath = r.URL.EscapedPath()
+		gotAuth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&doc); err != nil {
+			t.Errorf("body is not JSON: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	dir := writePackage(t, "@myorg/mytool-linux-x64", "1.0.0")
+	reg := registry{url: srv.URL + "/", token: "secret"}
+	tgz := packPackage(t, dir, "@myorg/mytool-linux-x64")
+	if err := registryPublish(t.Context(), reg, dir, tgz, "next", "restricted", false, nil); err != nil {
+		t.Fatalf("registryPublish: %v", err)
+	}
+
+	if gotPath != "/@myorg%2Fmytool-linux-x64" {
+		t.Errorf("path = %q, want /@myorg%%2Fmytool-linux-x64", gotPath)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want Bearer secret", gotAuth)
+	}
+	if doc["name"] != "@myorg/mytool-linux-x64" || doc["access"] != "restricted" {
+		t.Errorf("unexpected document: name=%v access=%v", doc["name"], doc["access"])
+	}
+	if tags, _ := doc["dist-tags"].(map[string]any); tags["next"] != "1.0.0" {
+		t.Errorf("dist-tags = %v, want next: 1.0.0", doc["dist-tags"])
+	}
+
+	version, _ := doc["versions"].(map[string]any)["1.0.0"].(map[string]any)
+	if version["_id"] != "@myorg/mytool-linux-x64@1.0.0" {
+		t.Errorf("version _id = %v", version["_id"])
+	}
+	dist, _ := version["dist"].(map[string]any)
+	if !strings.HasPrefix(dist["integrity"].(string), "sha512-") || dist["shasum"] == "" {
+		t.Errorf("dist = %v", dist)
+	}
+	if !strings.HasSuffix(dist["tarball"].(string), "/@myorg%2Fmytool-linux-x64/-/mytool-linux-x64-1.0.0.tgz") {
+		t.Errorf("dist.tarball = %v", dist["tarball"])
+	}
+
+	attachment, _ := doc["_attachments"].(map[string]any)["mytool-linux-x64-1.0.0.tgz"].(map[string]any)
+	data, err := base64.StdEncoding.DecodeString(attachment["data"].(string))
+	if err != nil {
+		t.Fatalf("attachment data is not base64: %v", err)
+	}
+	if int(attachment["length"].(float64)) != len(data) {
+		t.Errorf("attachment length = %v, want %d", attachment["length"], len(data))
+	}
+	if !bytes.Equal(data, tgz.data) {
+		t.Error("attachment data differs from the packed tarball")
+	}
+}
+
+func TestRegistryPublish_RetryResendsBody(t *testing.T) {
+	var bodies [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		if len(bodies) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	dir := writePackage(t, "mytool", "1.0.0")
+	reg := registry{url: srv.URL, token: "secret", client: httpclient.New(1, time.Second, nil)}
+	if err := registryPublish(t.Context(), reg, dir, packPackage(t, dir, "mytool"), "latest", "public", false, nil); err != nil {
+		t.Fatalf("registryPublish: %v", err)
+	}
+	if len(bodies) != 2 {
+		t.Fatalf("attempts = %d, want 2", len(bodies))
+	}
+	if len(bodies[1]) == 0 || !bytes.Equal(bodies[0], bodies[1]) {
+		t.Error("the retry didn't resend the same document")
+	}
+}
+
+func TestRegistryPublish_DryRun(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("dry run should not contact the registry")
+	}))
+	defer srv.Close()
+
+	dir := writePackage(t, "mytool", "1.0.0")
+	if err := registryPublish(t.Context(), registry{url: srv.URL}, dir, packPackage(t, dir, "mytool"), "latest", "public", true, nil); err != nil {
+		t.Fatalf("registryPublish: %v", err)
+	}
+}
+
+func TestRegistryVersionURL(t *testing.T) {
+	reg := registry{url: "https://registry.example.com/"}
+	if got, want := reg.versionURL("@myorg/mytool", "1.0.0-rc.1"), "https://registry.example.com/@myorg%2Fmytool/1.0.0-rc.1"; got != want {
+		t.Errorf("versionURL() = %q, want %q", got, want)
+	}
+}
+
+func TestRegistryError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		header  string
+		body    string
+		wantSub string
+	}{
+		{"otp header", http.StatusUnauthorized, "OTP", `{"error":"otp required"}`, "2FA"},
+		{"otp body", http.StatusUnauthorized, "", `{"error":"This operation requires a one-time password."}`, "2FA"},
+		{"unauthorized", http.StatusUnauthorized, "", `{"error":"unauthorized"}`, "not authenticated"},
+		{"forbidden", http.StatusForbidden, "", `{"error":"you do not have permission"}`, "permission denied"},
+		{"republish", http.StatusForbidden, "", `{"error":"You cannot publish over the previously published versions: 1.0.0."}`, "already exists"},
+		{"conflict", http.StatusConflict, "", `{"error":"conflict"}`, "already exists"},
+		{"other", http.StatusBadRequest, "", `{"error":"invalid name"}`, "registry returned 400: invalid name"},
+		{"non-json", http.StatusInternalServerError, "", "oops", "registry returned 500: oops"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(tt.body))}
+			if tt.header != "" {
+				resp.Header.Set("WWW-Authenticate", tt.header)
+			}
+			if got := registryError(resp); !strings.Contains(got, tt.wantSub) {
+				t.Errorf("registryError() = %q, want substring %q", got, tt.wantSub)
+			}
+		})
+	}
+}
+
+func TestRegistryPublish_MapsErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"You cannot publish over the previously published versions: 1.0.0."}`, http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	dir := writePackage(t, "mytool", "1.0.0")
+	err := registryPublish(t.Context(), registry{url: srv.URL, token: "secret"}, dir, packPackage(t, dir, "mytool"), "latest", "public", false, nil)
+	if err == nil || !strings.Contains(err.Error(), "version already exists") {
+		t.Errorf("registryPublish(t.Context(), ) error = %v, want version already exists", err)
+	}
+}
+
+func writeNpmrc(t *testing.T, project, user string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	userPath := filepath.Join(dir, "user.npmrc")
+	t.Setenv("NPM_CONFIG_USERCONFIG", userPath)
+	if err := os.WriteFile(userPath, []byte(user), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if project != "" {
+		if err := os.WriteFile(".npmrc", []byte(project), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestNpmrcToken(t *testing.T) {
+	writeNpmrc(t, "", "; user config\n//registry.npmjs.org/:_authToken=user-token\n//npm.example.com/:_authToken=other\n")
+	t.Setenv("MY_NPM_TOKEN", "from-env")
+
+	rc, err := loadNpmrc()
+	if err != nil {
+		t.Fatalf("loadNpmrc: %v", err)
+	}
+	if token, path := rc.token("https://registry.npmjs.org/"); token != "user-token" || path != os.Getenv("NPM_CONFIG_USERCONFIG") {
+		t.Errorf("token() = %q from %s, want user-token from the user config", token, path)
+	}
+
+	if err := os.WriteFile(".npmrc", []byte("registry=https://registry.npmjs.org/\n//registry.npmjs.org/:_authToken=${MY_NPM_TOKEN}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rc, err = loadNpmrc()
+	if err != nil {
+		t.Fatalf("loadNpmrc: %v", err)
+	}
+	if token, path := rc.token("https://registry.npmjs.org"); token != "from-env" || path != ".npmrc" {
+		t.Errorf("token() = %q from %s, want the project .npmrc with ${MY_NPM_TOKEN} expanded", token, path)
+	}
+	if token, _ := rc.token("https://npm.pkg.github.com/"); token != "" {
+		t.Errorf("token() for an unconfigured registry = %q, want empty", token)
+	}
+}
+
+func TestNpmrcRegistry(t *testing.T) {
+	writeNpmrc(t,
+		"@internal:registry=https://npm.internal.example.com/repository/npm/\n",
+		"registry=https://mirror.example.com/\n@internal:registry=https://ignored.example.com/\n",
+	)
+	rc, err := loadNpmrc()
+	if err != nil {
+		t.Fatalf("loadNpmrc: %v", err)
+	}
+
+	tests := []struct {
+		pkg  string
+		want string
+	}{
+		{"@internal/mytool-linux-x64", "https://npm.internal.example.com/repository/npm/"},
+		{"@other/mytool-linux-x64", "https://mirror.example.com/"},
+		{"mytool", "https://mirror.example.com/"},
+	}
+	for _, tt := range tests {
+		if got := rc.registry(tt.pkg); got != tt.want {
+			t.Errorf("registry(%q) = %q, want %q", tt.pkg, got, tt.want)
+		}
+	}
+	if got := registryKey("https://npm.internal.example.com/repository/npm"); got != "//npm.internal.example.com/repository/npm/" {
+		t.Errorf("registryKey() = %q", got)
+	}
+}
+
+func TestRegistries_ForPackage(t *testing.T) {
+	writeNpmrc(t,
+		"@internal:registry=https://npm.internal.example.com/\n//npm.internal.example.com/:_authToken=internal-token\n",
+		"//registry.npmjs.org/:_authToken=public-token\n",
+	)
+	t.Setenv("NODE_AUTH_TOKEN", "")
+
+	regs, err := newRegistries("", true, nil, nil)
+	if err != nil {
+		t.Fatalf("newRegistries: %v", err)
+	}
+	reg, err := regs.forPackage("@internal/mytool")
+	if err != nil || reg.url != "https://npm.internal.example.com/" || reg.token != "internal-token" {
+		t.Errorf("forPackage(@internal/mytool) = %+v, %v", reg, err)
+	}
+	reg, err = regs.forPackage("mytool")
+	if err != nil || reg.url != npmRegistryURL || reg.token != "public-token" {
+		t.Errorf("forPackage(mytool) = %+v, %v", reg, err)
+	}
+
+	regs, err = newRegistries("https://npm.internal.example.com/", true, nil, nil)
+	if err != nil {
+		t.Fatalf("newRegistries: %v", err)
+	}
+	reg, err = regs.forPackage("mytool")
+	if err != nil || reg.url != "https://npm.internal.example.com/" || reg.token != "internal-token" {
+		t.Errorf("forPackage(mytool) with --registry = %+v, %v", reg, err)
+	}
+}
+
+func TestRegistries_EnvToken(t *testing.T) {
+	writeNpmrc(t, "", "//registry.npmjs.org/:_authToken=user-token\n")
+	t.Setenv("NODE_AUTH_TOKEN", "env-token")
+	regs, err := newRegistries("", true, nil, nil)
+	if err != nil {
+		t.Fatalf("newRegistries: %v", err)
+	}
+	if reg, err := regs.forPackage("mytool"); err != nil || reg.token != "env-token" {
+		t.Errorf("forPackage() = %+v, %v, want env-token", reg, err)
+	}
+}
+
+func TestRegistries_MissingToken(t *testing.T) {
+	writeNpmrc(t, "", "")
+	t.Setenv("NODE_AUTH_TOKEN", "")
+
+	regs, err := newRegistries("https://npm.internal.example.com", true, nil, nil)
+	if err != nil {
+		t.Fatalf("newRegistries: %v", err)
+	}
+	_, err = regs.forPackage("mytool")
+	if err == nil || !strings.Contains(err.Error(), "//npm.internal.example.com/:_authToken") {
+		t.Errorf("forPackage() error = %v, want a hint about .npmrc", err)
+	}
+
+	regs, err = newRegistries("", false, nil, nil)
+	if err != nil {
+		t.Fatalf("newRegistries: %v", err)
+	}
+	if _, err := regs.forPackage("mytool"); err != nil {
+		t.Errorf("forPackage() without auth = %v, want no error", err)
+	}
+}
+
+func TestPollUntilVisible_Auth(t *testing.T) {
+	var gotPath, gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth = r.URL.Path, r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	reg := registry{url: srv.URL + "/npm/", token: "secret"}
+	if err := pollUntilVisible(t.Context(), reg, "@myorg/mytool-linux-x64", "1.0.0"); err != nil {
+		t.Fatalf("pollUntilVisible: %v", err)
+	}
+	if gotPath != "/npm/@myorg/mytool-linux-x64/1.0.0" {
+		t.Errorf("path = %q", gotPath)
+	}
+	if gotAuth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want Bearer secret", gotAuth)
+	}
+}
+
+func TestPollUntilVisible_Canceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := pollUntilVisible(ctx, registry{url: srv.URL}, "mytool", "1.0.0")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("pollUntilVisible() = %v, want the context's error", err)
+	}
+	if elapsed := time.Since(start); elapsed > registryPollInterval {
+		t.Errorf("pollUntilVisible() took %s after cancellation", elapsed)
+	}
+}
@@ -0,0 +1,146 @@
+package npm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// npm pins every entry to this date so identical contents give identical tarballs.
+var tarballModTime = time.Date(1985, time.October, 26, 8, 15, 0, 0, time.UTC)
+
+type tarball struct {
+	filename  string
+	data      []byte
+	shasum    string
+	integrity string
+}
+
+func packTarball(dir, name, version string) (tarball, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:     "package/" + filepath.ToSlash(rel),
+			Mode:     int64(fileMode(path)),
+			Size:     int64(len(data)),
+			ModTime:  tarballModTime,
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err = tw.Write(data)
+		return err
+	})
+	if err != nil {
+		return tarball{}, fmt.Errorf("failed to pack %s: %w", name, err)
+	}
+	if err := tw.Close(); err != nil {
+		return tarball{}, fmt.Errorf("failed to pack %s: %w", name, err)
+	}
+	if err := gw.Close(); err != nil {
+		return tarball{}, fmt.Errorf("failed to pack %s: %w", name, err)
+	}
+
+	data := buf.Bytes()
+	sha1Sum := sha1.Sum(data)
+	sha512Sum := sha512.Sum512(data)
+	return tarball{
+		filename:  fmt.Sprintf("%s-%s.tgz", unscopedName(name), version),
+		data:      data,
+		shasum:    hex.EncodeToString(sha1Sum[:]),
+		integrity: "sha512-" + base64.StdEncoding.EncodeToString(sha512Sum[:]),
+	}, nil
+}
+
+func tarballFiles(data []byte) (map[string][]byte, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+	files := make(map[string][]byte)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if files[hdr.Name], err = io.ReadAll(tr); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sameContents reports whether two tarballs hold the same files. Tarballs
+// packed by the npm CLI differ from ours byte for byte, and package.json may
+// be reformatted, so it is compared as JSON.
+func sameContents(a, b []byte) (bool, error) {
+	filesA, err := tarballFiles(a)
+	if err != nil {
+		return false, err
+	}
+	filesB, err := tarballFiles(b)
+	if err != nil {
+		return false, err
+	}
+	if len(filesA) != len(filesB) {
+		return false, nil
+	}
+	for name, dataA := range filesA {
+		dataB, ok := filesB[name]
+		if !ok {
+			return false, nil
+		}
+		if name == "package/package.json" {
+			var jsonA, jsonB any
+			if json.Unmarshal(dataA, &jsonA) != nil || json.Unmarshal(dataB, &jsonB) != nil || !reflect.DeepEqual(jsonA, jsonB) {
+				return false, nil
+			}
+			continue
+		}
+		if !bytes.Equal(dataA, dataB) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func unscopedName(name string) string {
+	if _, after, ok := strings.Cut(name, "/"); ok && strings.HasPrefix(name, "@") {
+		return after
+	}
+	return name
+}
@@ -0,0 +1,140 @@
+package npm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPackTarball(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name":"@myorg/mytool-linux-x64"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bin", "mytool"), []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tgz, err := packTarball(dir, "@myorg/mytool-linux-x64", "1.2.3")
+	if err != nil {
+		t.Fatalf("packTarball: %v", err)
+	}
+	if tgz.filename != "mytool-linux-x64-1.2.3.tgz" {
+		t.Errorf("filename = %q, want mytool-linux-x64-1.2.3.tgz", tgz.filename)
+	}
+	sum := sha512.Sum512(tgz.data)
+	if want := "sha512-" + base64.StdEncoding.EncodeToString(sum[:]); tgz.integrity != want {
+		t.Errorf("integrity = %q, want %q", tgz.integrity, want)
+	}
+	if len(tgz.shasum) != 40 {
+		t.Errorf("shasum = %q, want a hex sha1", tgz.shasum)
+	}
+
+	gr, err := gzip.NewReader(bytes.NewReader(tgz.data))
+	if err != nil {
+		t.Fatalf("tarball is not gzipped: %v", err)
+	}
+	tr := tar.NewReader(gr)
+	modes := make(map[string]int64)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		modes[hdr.Name] = hdr.Mode
+		if !hdr.ModTime.Equal(tarballModTime) {
+			t.Errorf("%s mtime = %v, want %v", hdr.Name, hdr.ModTime, tarballModTime)
+		}
+	}
+	want := map[string]int64{"package/bin/mytool": 0755, "package/package.json": 0644}
+	if len(modes) != len(want) {
+		t.Errorf("entries = %v, want %v", modes, want)
+	}
+	for name, mode := range want {
+		if modes[name] != mode {
+			t.Errorf("%s mode = %o, want %o", name, modes[name], mode)
+		}
+	}
+
+	again, err := packTarball(dir, "@myorg/mytool-linux-x64", "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.integrity != tgz.integrity {
+		t.Error("packing the same directory twice should give the same tarball")
+	}
+}
+
+func TestSameContents(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name":"mytool","version":"1.0.0"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.js"), []byte("main"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ours, err := packTarball(dir, "mytool", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	files := []struct{ name, data string }{
+		{"package/index.js", "main"},
+		{"package/package.json", "{\n  \"version\": \"1.0.0\",\n  \"name\": \"mytool\"\n}\n"},
+	}
+	for _, f := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if same, err := sameContents(ours.data, buf.Bytes()); err != nil || !same {
+		t.Errorf("sameContents() = %v, %v for a reformatted package.json, want true", same, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "index.js"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := packTarball(dir, "mytool", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same, err := sameContents(changed.data, buf.Bytes()); err != nil || same {
+		t.Errorf("sameContents() = %v, %v for a changed file, want false", same, err)
+	}
+}
+
+func TestUnscopedName(t *testing.T) {
+	for name, want := range map[string]string{
+		"@myorg/mytool": "mytool",
+		"mytool":        "mytool",
+	} {
+		if got := unscopedName(name); got != want {
+			t.Errorf("unscopedName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
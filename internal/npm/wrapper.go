@@ -8,7 +8,10 @@ import (
 //go:embed wrapper.js
 var wrapperJS string
 
-func wrapperScript(name, org string) string {
-	s := strings.ReplaceAll(wrapperJS, "__BIN_NAME__", name)
-	return strings.ReplaceAll(s, "__ORG_NAME__", org)
+func wrapperScript(pkgName, binName, org string) string {
+	return strings.NewReplacer(
+		"__PKG_NAME__", pkgName,
+		"__BIN_NAME__", binName,
+		"__ORG_NAME__", org,
+	).Replace(wrapperJS)
 }
@@ -2,23 +2,114 @@
 "use strict";
 
 const { execFileSync } = require("child_process");
+const os = require("os");
 
+const PKG_NAME = "__PKG_NAME__";
 const BIN_NAME = "__BIN_NAME__";
 const ORG_NAME = "__ORG_NAME__";
 
 const platforms = {
-  "linux-x64":    `@${ORG_NAME}/${BIN_NAME}-linux-x64`,
-  "linux-arm64":  `@${ORG_NAME}/${BIN_NAME}-linux-arm64`,
-  "darwin-x64":   `@${ORG_NAME}/${BIN_NAME}-darwin-x64`,
-  "darwin-arm64": `@${ORG_NAME}/${BIN_NAME}-darwin-arm64`,
-  "win32-x64":    `@${ORG_NAME}/${BIN_NAME}-win32-x64`,
-  "win32-arm64":  `@${ORG_NAME}/${BIN_NAME}-win32-arm64`,
+  "linux-x64":          `@${ORG_NAME}/${PKG_NAME}-linux-x64`,
+  "linux-arm64":        `@${ORG_NAME}/${PKG_NAME}-linux-arm64`,
+  "linux-ia32":         `@${ORG_NAME}/${PKG_NAME}-linux-ia32`,
+  "linux-arm":          `@${ORG_NAME}/${PKG_NAME}-linux-arm`,
+  "linux-armv6":        `@${ORG_NAME}/${PKG_NAME}-linux-armv6`,
+  "linux-riscv64":      `@${ORG_NAME}/${PKG_NAME}-linux-riscv64`,
+  "linux-ppc64":        `@${ORG_NAME}/${PKG_NAME}-linux-ppc64`,
+  "linux-s390x":        `@${ORG_NAME}/${PKG_NAME}-linux-s390x`,
+  "linux-x64-musl":     `@${ORG_NAME}/${PKG_NAME}-linux-x64-musl`,
+  "linux-arm64-musl":   `@${ORG_NAME}/${PKG_NAME}-linux-arm64-musl`,
+  "linux-ia32-musl":    `@${ORG_NAME}/${PKG_NAME}-linux-ia32-musl`,
+  "linux-arm-musl":     `@${ORG_NAME}/${PKG_NAME}-linux-arm-musl`,
+  "linux-riscv64-musl": `@${ORG_NAME}/${PKG_NAME}-linux-riscv64-musl`,
+  "linux-ppc64-musl":   `@${ORG_NAME}/${PKG_NAME}-linux-ppc64-musl`,
+  "linux-s390x-musl":   `@${ORG_NAME}/${PKG_NAME}-linux-s390x-musl`,
+  "darwin-x64":         `@${ORG_NAME}/${PKG_NAME}-darwin-x64`,
+  "darwin-arm64":       `@${ORG_NAME}/${PKG_NAME}-darwin-arm64`,
+  "win32-x64":          `@${ORG_NAME}/${PKG_NAME}-win32-x64`,
+  "win32-arm64":        `@${ORG_NAME}/${PKG_NAME}-win32-arm64`,
+  "freebsd-x64":        `@${ORG_NAME}/${PKG_NAME}-freebsd-x64`,
+  "freebsd-arm64":      `@${ORG_NAME}/${PKG_NAME}-freebsd-arm64`,
 };
 
-const key = `${process.platform}-${process.arch === "x64" ? "x64" : process.arch}`;
-const pkg = platforms[key];
+function isMusl() {
+  if (process.platform !== "linux") {
+    return false;
+  }
+  try {
+    const report = process.report.getReport();
+    const header = typeof report === "string" ? JSON.parse(report).header : report.header;
+    return !header.glibcVersionRuntime;
+  } catch (e) {
+    // fall back to asking ldd
+  }
+  try {
+    return execFileSync("ldd", ["--version"], { encoding: "utf8", stdio: "pipe" }).includes("musl");
+  } catch (e) {
+    return String(e.stderr ?? "").includes("musl");
+  }
+}
+
+// ARMv7 devices can also run ARMv6 binaries, so fall back to them. On musl,
+// prefer the musl package and fall back to a statically linked default one.
+function candidateKeys() {
+  const base = `${process.platform}-${process.arch}`;
+  let keys = [base];
+  if (process.arch === "arm") {
+    const armVersion = Number(process.config.variables.arm_version) || 7;
+    keys = armVersion >= 7 ? [base, `${base}v6`] : [`${base}v6`];
+  }
+  if (isMusl()) {
+    keys = keys.flatMap((k) => [`${k}-musl`, k]);
+  }
+  return keys;
+}
+
+function isOlder(version, min) {
+  const a = version.split(".").map(Number);
+  const b = min.split(".").map(Number);
+  for (let i = 0; i < b.length; i++) {
+    if ((a[i] || 0) !== b[i]) {
+      return (a[i] || 0) < b[i];
+    }
+  }
+  return false;
+}
+
+// Darwin 20 is macOS 11, so a newer kernel already rules out most old systems
+// without having to spawn sw_vers.
+function checkMinOS(pkg) {
+  if (process.platform !== "darwin") {
+    return;
+  }
+  let minOS;
+  try {
+    minOS = require(`${pkg}/package.json`).shipbin?.minOS;
+  } catch (e) {
+    return;
+  }
+  if (!minOS) {
+    return;
+  }
+  const kernel = Number(os.release().split(".")[0]);
+  if (kernel >= 20 && kernel - 9 > Number(minOS.split(".")[0])) {
+    return;
+  }
+  let current;
+  try {
+    current = execFileSync("sw_vers", ["-productVersion"], { encoding: "utf8", stdio: "pipe" }).trim();
+  } catch (e) {
+    return;
+  }
+  if (isOlder(current, minOS)) {
+    console.error(`${BIN_NAME}: requires macOS ${minOS} or newer, but this is macOS ${current}`);
+    process.exit(1);
+  }
+}
 
-if (!pkg) {
+const keys = candidateKeys().filter((k) => platforms[k]);
+
+if (keys.length === 0) {
   console.error(
     `${BIN_NAME}: unsupported platform ${process.platform}/${process.arch}\n` +
     `supported platforms: ${Object.keys(platforms).join(", ")}`
@@ -26,17 +117,30 @@ if (!pkg) {
   process.exit(1);
 }
 
+const binFile = process.platform === "win32" ? `${BIN_NAME}.exe` : BIN_NAME;
+
 let binPath;
-try {
-  binPath = require.resolve(`${pkg}/bin/${BIN_NAME}`);
-} catch (e) {
+let binPkg;
+for (const key of keys) {
+  try {
+    binPath = require.resolve(`${platforms[key]}/bin/${binFile}`);
+    binPkg = platforms[key];
+    break;
+  } catch (e) {
+    // try the next candidate
+  }
+}
+
+if (!binPath) {
   console.error(
-    `${BIN_NAME}: could not find platform package ${pkg}\n` +
-    `try reinstalling: npm install -g ${BIN_NAME}`
+    `${BIN_NAME}: could not find platform package ${keys.map((k) => platforms[k]).join(" or ")}\n` +
+    `try reinstalling: npm install -g ${PKG_NAME}`
   );
   process.exit(1);
 }
 
+checkMinOS(binPkg);
+
 try {
   execFileSync(binPath, process.argv.slice(2), { stdio: "inherit" });
 } catch (e) {
@@ -3,10 +3,12 @@ package npm
 import (
 	"strings"
 	"testing"
+
+	"github.com/jacobarthurs/shipbin/internal/platforms"
 )
 
 func TestWrapperScript_ReplacesPlaceholders(t *testing.T) {
-	script := wrapperScript("mytool", "myorg")
+	script := wrapperScript("mytool", "mytool", "myorg")
 
 	if strings.Contains(script, "__BIN_NAME__") {
 		t.Error("wrapperScript did not replace __BIN_NAME__")
@@ -14,10 +16,24 @@ func TestWrapperScript_ReplacesPlaceholders(t *testing.T) {
 	if strings.Contains(script, "__ORG_NAME__") {
 		t.Error("wrapperScript did not replace __ORG_NAME__")
 	}
+	if strings.Contains(script, "__PKG_NAME__") {
+		t.Error("wrapperScript did not replace __PKG_NAME__")
+	}
+}
+
+func TestWrapperScript_SeparatesPackageAndBinary(t *testing.T) {
+	script := wrapperScript("mytool", "mytool-lsp", "acme")
+
+	if !strings.Contains(script, `const PKG_NAME = "mytool"`) {
+		t.Error(`script does not contain: const PKG_NAME = "mytool"`)
+	}
+	if !strings.Contains(script, `const BIN_NAME = "mytool-lsp"`) {
+		t.Error(`script does not contain: const BIN_NAME = "mytool-lsp"`)
+	}
 }
 
 func TestWrapperScript_ContainsPackageReferences(t *testing.T) {
-	script := wrapperScript("cli", "acme")
+	script := wrapperScript("cli", "cli", "acme")
 
 	if !strings.Contains(script, `const BIN_NAME = "cli"`) {
 		t.Error(`script does not contain: const BIN_NAME = "cli"`)
@@ -28,7 +44,7 @@ func TestWrapperScript_ContainsPackageReferences(t *testing.T) {
 }
 
 func TestWrapperScript_ContainsRuntimeCalls(t *testing.T) {
-	script := wrapperScript("mytool", "myorg")
+	script := wrapperScript("mytool", "mytool", "myorg")
 
 	if !strings.Contains(script, "require.resolve") {
 		t.Error("script does not contain require.resolve")
@@ -36,11 +52,14 @@ func TestWrapperScript_ContainsRuntimeCalls(t *testing.T) {
 	if !strings.Contains(script, "execFileSync") {
 		t.Error("script does not contain execFileSync")
 	}
+	if !strings.Contains(script, "shipbin?.minOS") {
+		t.Error("script does not check the platform package's minimum OS")
+	}
 }
 
 func TestWrapperScript_DifferentInputs(t *testing.T) {
-	s1 := wrapperScript("foo", "bar")
-	s2 := wrapperScript("baz", "qux")
+	s1 := wrapperScript("foo", "foo", "bar")
+	s2 := wrapperScript("baz", "baz", "qux")
 
 	if s1 == s2 {
 		t.Error("scripts with different inputs should differ")
@@ -52,3 +71,20 @@ func TestWrapperScript_DifferentInputs(t *testing.T) {
 		t.Error("s2 should contain 'baz' but not 'foo'")
 	}
 }
+
+func TestWrapperScript_KnowsEveryPlatform(t *testing.T) {
+	script := wrapperScript("mytool", "mytool", "myorg")
+
+	for _, p := range platforms.All() {
+		m, err := platforms.Lookup(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Npm.PackageSuffix == "" {
+			continue
+		}
+		if !strings.Contains(script, `"`+m.Npm.PackageSuffix+`":`) {
+			t.Errorf("wrapper.js has no entry for %s (%s)", p, m.Npm.PackageSuffix)
+		}
+	}
+}
@@ -4,21 +4,39 @@ import (
 	"cmp"
 	"fmt"
 	"slices"
+	"strings"
 )
 
 type Platform struct {
 	GOOS   string
 	GOARCH string
+	GOARM  string
+	Libc   string
+}
+
+func (p Platform) String() string {
+	s := p.GOOS
+	if p.Libc != "" {
+		s += "-" + p.Libc
+	}
+	s += "/" + p.GOARCH
+	if p.GOARM != "" {
+		s += "/v" + p.GOARM
+	}
+	return s
 }
 
 type NpmMapping struct {
 	OS            string
 	CPU           string
+	Libc          string
 	PackageSuffix string
 }
 
 type PyPIMapping struct {
-	WheelTag string
+	WheelTag      string
+	ManylinuxArch string
+	MacOSArch     string
 }
 
 type Mapping struct {
@@ -29,19 +47,22 @@ type Mapping struct {
 var table = map[Platform]Mapping{
 	{GOOS: "linux", GOARCH: "amd64"}: {
 		Npm:  NpmMapping{OS: "linux", CPU: "x64", PackageSuffix: "linux-x64"},
-		PyPI: PyPIMapping{WheelTag: "manylinux_2_17_x86_64.manylinux2014_x86_64"},
+		PyPI: PyPIMapping{WheelTag: "manylinux_2_17_x86_64.manylinux2014_x86_64", ManylinuxArch: "x86_64"},
 	},
 	{GOOS: "linux", GOARCH: "arm64"}: {
 		Npm:  NpmMapping{OS: "linux", CPU: "arm64", PackageSuffix: "linux-arm64"},
-		PyPI: PyPIMapping{WheelTag: "manylinux_2_17_aarch64.manylinux2014_aarch64"},
+		PyPI: PyPIMapping{WheelTag: "manylinux_2_17_aarch64.manylinux2014_aarch64", ManylinuxArch: "aarch64"},
 	},
 	{GOOS: "darwin", GOARCH: "amd64"}: {
 		Npm:  NpmMapping{OS: "darwin", CPU: "x64", PackageSuffix: "darwin-x64"},
-		PyPI: PyPIMapping{WheelTag: "macosx_10_12_x86_64"},
+		PyPI: PyPIMapping{WheelTag: "macosx_10_12_x86_64", MacOSArch: "x86_64"},
 	},
 	{GOOS: "darwin", GOARCH: "arm64"}: {
 		Npm:  NpmMapping{OS: "darwin", CPU: "arm64", PackageSuffix: "darwin-arm64"},
-		PyPI: PyPIMapping{WheelTag: "macosx_11_0_arm64"},
+		PyPI: PyPIMapping{WheelTag: "macosx_11_0_arm64", MacOSArch: "arm64"},
+	},
+	{GOOS: "darwin", GOARCH: "universal"}: {
+		PyPI: PyPIMapping{WheelTag: "macosx_11_0_universal2", MacOSArch: "universal2"},
 	},
 	{GOOS: "windows", GOARCH: "amd64"}: {
 		Npm:  NpmMapping{OS: "win32", CPU: "x64", PackageSuffix: "win32-x64"},
@@ -51,12 +72,119 @@ var table = map[Platform]Mapping{
 		Npm:  NpmMapping{OS: "win32", CPU: "arm64", PackageSuffix: "win32-arm64"},
 		PyPI: PyPIMapping{WheelTag: "win_arm64"},
 	},
+	{GOOS: "linux", GOARCH: "386"}: {
+		Npm:  NpmMapping{OS: "linux", CPU: "ia32", PackageSuffix: "linux-ia32"},
+		PyPI: PyPIMapping{WheelTag: "manylinux_2_17_i686.manylinux2014_i686", ManylinuxArch: "i686"},
+	},
+	{GOOS: "linux", GOARCH: "arm", GOARM: "7"}: {
+		Npm:  NpmMapping{OS: "linux", CPU: "arm", PackageSuffix: "linux-arm"},
+		PyPI: PyPIMapping{WheelTag: "manylinux_2_17_armv7l.manylinux2014_armv7l", ManylinuxArch: "armv7l"},
+	},
+	{GOOS: "linux", GOARCH: "arm", GOARM: "6"}: {
+		Npm:  NpmMapping{OS: "linux", CPU: "arm", PackageSuffix: "linux-armv6"},
+		PyPI: PyPIMapping{WheelTag: "linux_armv6l"},
+	},
+	{GOOS: "linux", GOARCH: "riscv64"}: {
+		Npm:  NpmMapping{OS: "linux", CPU: "riscv64", PackageSuffix: "linux-riscv64"},
+		PyPI: PyPIMapping{WheelTag: "linux_riscv64"},
+	},
+	{GOOS: "linux", GOARCH: "ppc64le"}: {
+		Npm:  NpmMapping{OS: "linux", CPU: "ppc64", PackageSuffix: "linux-ppc64"},
+		PyPI: PyPIMapping{WheelTag: "manylinux_2_17_ppc64le.manylinux2014_ppc64le", ManylinuxArch: "ppc64le"},
+	},
+	{GOOS: "linux", GOARCH: "s390x"}: {
+		Npm:  NpmMapping{OS: "linux", CPU: "s390x", PackageSuffix: "linux-s390x"},
+		PyPI: PyPIMapping{WheelTag: "manylinux_2_17_s390x.manylinux2014_s390x", ManylinuxArch: "s390x"},
+	},
+	{GOOS: "linux", GOARCH: "amd64", Libc: "musl"}: {
+		Npm:  NpmMapping{OS: "linux", CPU: "x64", Libc: "musl", PackageSuffix: "linux-x64-musl"},
+		PyPI: PyPIMapping{WheelTag: "musllinux_1_2_x86_64"},
+	},
+	{GOOS: "linux", GOARCH: "arm64", Libc: "musl"}: {
+		Npm:  NpmMapping{OS: "linux", CPU: "arm64", Libc: "musl", PackageSuffix: "linux-arm64-musl"},
+		PyPI: PyPIMapping{WheelTag: "musllinux_1_2_aarch64"},
+	},
+	{GOOS: "linux", GOARCH: "386", Libc: "musl"}: {
+		Npm:  NpmMapping{OS: "linux", CPU: "ia32", Libc: "musl", PackageSuffix: "linux-ia32-musl"},
+		PyPI: PyPIMapping{WheelTag: "musllinux_1_2_i686"},
+	},
+	{GOOS: "linux", GOARCH: "arm", GOARM: "7", Libc: "musl"}: {
+		Npm:  NpmMapping{OS: "linux", CPU: "arm", Libc: "musl", PackageSuffix: "linux-arm-musl"},
+		PyPI: PyPIMapping{WheelTag: "musllinux_1_2_armv7l"},
+	},
+	{GOOS: "linux", GOARCH: "riscv64", Libc: "musl"}: {
+		Npm:  NpmMapping{OS: "linux", CPU: "riscv64", Libc: "musl", PackageSuffix: "linux-riscv64-musl"},
+		PyPI: PyPIMapping{WheelTag: "musllinux_1_2_riscv64"},
+	},
+	{GOOS: "linux", GOARCH: "ppc64le", Libc: "musl"}: {
+		Npm:  NpmMapping{OS: "linux", CPU: "ppc64", Libc: "musl", PackageSuffix: "linux-ppc64-musl"},
+		PyPI: PyPIMapping{WheelTag: "musllinux_1_2_ppc64le"},
+	},
+	{GOOS: "linux", GOARCH: "s390x", Libc: "musl"}: {
+		Npm:  NpmMapping{OS: "linux", CPU: "s390x", Libc: "musl", PackageSuffix: "linux-s390x-musl"},
+		PyPI: PyPIMapping{WheelTag: "musllinux_1_2_s390x"},
+	},
+	{GOOS: "freebsd", GOARCH: "amd64"}: {
+		Npm: NpmMapping{OS: "freebsd", CPU: "x64", PackageSuffix: "freebsd-x64"},
+	},
+	{GOOS: "freebsd", GOARCH: "arm64"}: {
+		Npm: NpmMapping{OS: "freebsd", CPU: "arm64", PackageSuffix: "freebsd-arm64"},
+	},
+}
+
+var goarmVersions = []string{"6", "7"}
+
+var universalArches = []string{"amd64", "arm64"}
+
+const defaultGOARM = "7"
+
+func (p Platform) IsUniversal() bool {
+	return p.GOOS == "darwin" && p.GOARCH == "universal"
 }
 
-func Lookup(goos, goarch string) (Mapping, error) {
-	m, ok := table[Platform{GOOS: goos, GOARCH: goarch}]
+func (p Platform) Slices() []Platform {
+	if !p.IsUniversal() {
+		return []Platform{p}
+	}
+	result := make([]Platform, 0, len(universalArches))
+	for _, arch := range universalArches {
+		result = append(result, Platform{GOOS: p.GOOS, GOARCH: arch})
+	}
+	return result
+}
+
+func Parse(s string) (Platform, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return Platform{}, fmt.Errorf("platform %q must be os/arch", s)
+	}
+
+	p := Platform{GOOS: parts[0], GOARCH: parts[1]}
+	if goos, libc, ok := strings.Cut(p.GOOS, "-"); ok {
+		if goos != "linux" || libc != "musl" {
+			return Platform{}, fmt.Errorf("platform %q: only linux-musl is supported as a libc variant", s)
+		}
+		p.GOOS, p.Libc = goos, libc
+	}
+	if p.GOARCH == "arm" {
+		p.GOARM = defaultGOARM
+	}
+	if len(parts) == 3 {
+		if p.GOARCH != "arm" {
+			return Platform{}, fmt.Errorf("platform %q: variants are only supported for arm", s)
+		}
+		p.GOARM = strings.TrimPrefix(parts[2], "v")
+		if !slices.Contains(goarmVersions, p.GOARM) {
+			return Platform{}, fmt.Errorf("platform %q: unsupported arm variant %q (supported: v6, v7)", s, parts[2])
+		}
+	}
+	return p, nil
+}
+
+func Lookup(p Platform) (Mapping, error) {
+	m, ok := table[p]
 	if !ok {
-		return Mapping{}, fmt.Errorf("unsupported platform: %s/%s", goos, goarch)
+		return Mapping{}, fmt.Errorf("unsupported platform: %s", p)
 	}
 	return m, nil
 }
@@ -70,7 +198,13 @@ func All() []Platform {
 		if a.GOOS != b.GOOS {
 			return cmp.Compare(a.GOOS, b.GOOS)
 		}
-		return cmp.Compare(a.GOARCH, b.GOARCH)
+		if a.GOARCH != b.GOARCH {
+			return cmp.Compare(a.GOARCH, b.GOARCH)
+		}
+		if a.GOARM != b.GOARM {
+			return cmp.Compare(a.GOARM, b.GOARM)
+		}
+		return cmp.Compare(a.Libc, b.Libc)
 	})
 	return result
 }
@@ -6,26 +6,41 @@ import (
 
 func TestLookup(t *testing.T) {
 	tests := []struct {
-		goos          string
-		goarch        string
+		platform      string
 		wantNpmSuffix string
 		wantNpmOS     string
 		wantNpmCPU    string
 		wantWheelTag  string
 	}{
-		{"linux", "amd64", "linux-x64", "linux", "x64", "manylinux_2_17_x86_64.manylinux2014_x86_64"},
-		{"linux", "arm64", "linux-arm64", "linux", "arm64", "manylinux_2_17_aarch64.manylinux2014_aarch64"},
-		{"darwin", "amd64", "darwin-x64", "darwin", "x64", "macosx_10_12_x86_64"},
-		{"darwin", "arm64", "darwin-arm64", "darwin", "arm64", "macosx_11_0_arm64"},
-		{"windows", "amd64", "win32-x64", "win32", "x64", "win_amd64"},
-		{"windows", "arm64", "win32-arm64", "win32", "arm64", "win_arm64"},
+		{"linux/amd64", "linux-x64", "linux", "x64", "manylinux_2_17_x86_64.manylinux2014_x86_64"},
+		{"linux/arm64", "linux-arm64", "linux", "arm64", "manylinux_2_17_aarch64.manylinux2014_aarch64"},
+		{"darwin/amd64", "darwin-x64", "darwin", "x64", "macosx_10_12_x86_64"},
+		{"darwin/arm64", "darwin-arm64", "darwin", "arm64", "macosx_11_0_arm64"},
+		{"darwin/universal", "", "", "", "macosx_11_0_universal2"},
+		{"windows/amd64", "win32-x64", "win32", "x64", "win_amd64"},
+		{"windows/arm64", "win32-arm64", "win32", "arm64", "win_arm64"},
+		{"linux/386", "linux-ia32", "linux", "ia32", "manylinux_2_17_i686.manylinux2014_i686"},
+		{"linux/arm/v7", "linux-arm", "linux", "arm", "manylinux_2_17_armv7l.manylinux2014_armv7l"},
+		{"linux/arm/v6", "linux-armv6", "linux", "arm", "linux_armv6l"},
+		{"linux/riscv64", "linux-riscv64", "linux", "riscv64", "linux_riscv64"},
+		{"linux/ppc64le", "linux-ppc64", "linux", "ppc64", "manylinux_2_17_ppc64le.manylinux2014_ppc64le"},
+		{"linux/s390x", "linux-s390x", "linux", "s390x", "manylinux_2_17_s390x.manylinux2014_s390x"},
+		{"linux-musl/amd64", "linux-x64-musl", "linux", "x64", "musllinux_1_2_x86_64"},
+		{"linux-musl/arm64", "linux-arm64-musl", "linux", "arm64", "musllinux_1_2_aarch64"},
+		{"linux-musl/arm/v7", "linux-arm-musl", "linux", "arm", "musllinux_1_2_armv7l"},
+		{"freebsd/amd64", "freebsd-x64", "freebsd", "x64", ""},
+		{"freebsd/arm64", "freebsd-arm64", "freebsd", "arm64", ""},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.goos+"/"+tt.goarch, func(t *testing.T) {
-			m, err := Lookup(tt.goos, tt.goarch)
+		t.Run(tt.platform, func(t *testing.T) {
+			p, err := Parse(tt.platform)
 			if err != nil {
-				t.Fatalf("Lookup(%q, %q) unexpected error: %v", tt.goos, tt.goarch, err)
+				t.Fatalf("Parse(%q) unexpected error: %v", tt.platform, err)
+			}
+			m, err := Lookup(p)
+			if err != nil {
+				t.Fatalf("Lookup(%s) unexpected error: %v", p, err)
 			}
 			if m.Npm.PackageSuffix != tt.wantNpmSuffix {
 				t.Errorf("Npm.PackageSuffix = %q, want %q", m.Npm.PackageSuffix, tt.wantNpmSuffix)
@@ -44,39 +59,104 @@ func TestLookup(t *testing.T) {
 }
 
 func TestLookup_Unsupported(t *testing.T) {
-	cases := [][2]string{
-		{"freebsd", "amd64"},
-		{"linux", "386"},
-		{"", ""},
-		{"windows", "x86"},
-		{"darwin", "386"},
-	}
-	for _, c := range cases {
-		_, err := Lookup(c[0], c[1])
-		if err == nil {
-			t.Errorf("Lookup(%q, %q) expected error, got nil", c[0], c[1])
+	cases := []Platform{
+		{GOOS: "freebsd", GOARCH: "386"},
+		{GOOS: "linux", GOARCH: "mips"},
+		{GOOS: "linux", GOARCH: "arm"},
+		{GOOS: "linux", GOARCH: "arm", GOARM: "5"},
+		{},
+		{GOOS: "windows", GOARCH: "x86"},
+		{GOOS: "darwin", GOARCH: "386"},
+		{GOOS: "linux", GOARCH: "universal"},
+	}
+	for _, p := range cases {
+		if _, err := Lookup(p); err == nil {
+			t.Errorf("Lookup(%+v) expected error, got nil", p)
 		}
 	}
 }
 
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Platform
+	}{
+		{"linux/amd64", Platform{GOOS: "linux", GOARCH: "amd64"}},
+		{"linux/arm", Platform{GOOS: "linux", GOARCH: "arm", GOARM: "7"}},
+		{"linux/arm/v6", Platform{GOOS: "linux", GOARCH: "arm", GOARM: "6"}},
+		{"linux/arm/7", Platform{GOOS: "linux", GOARCH: "arm", GOARM: "7"}},
+		{"linux-musl/amd64", Platform{GOOS: "linux", GOARCH: "amd64", Libc: "musl"}},
+		{"linux-musl/arm/v7", Platform{GOOS: "linux", GOARCH: "arm", GOARM: "7", Libc: "musl"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) unexpected error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	for _, bad := range []string{"linux", "linux/", "/amd64", "linux/amd64/v2", "linux/arm/v5", "linux/arm/v7/x", "darwin-musl/arm64", "linux-uclibc/amd64"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) expected error, got nil", bad)
+		}
+	}
+}
+
+func TestPlatformString(t *testing.T) {
+	if got := (Platform{GOOS: "linux", GOARCH: "amd64"}).String(); got != "linux/amd64" {
+		t.Errorf("String() = %q, want linux/amd64", got)
+	}
+	if got := (Platform{GOOS: "linux", GOARCH: "arm", GOARM: "6"}).String(); got != "linux/arm/v6" {
+		t.Errorf("String() = %q, want linux/arm/v6", got)
+	}
+	if got := (Platform{GOOS: "linux", GOARCH: "arm", GOARM: "7", Libc: "musl"}).String(); got != "linux-musl/arm/v7" {
+		t.Errorf("String() = %q, want linux-musl/arm/v7", got)
+	}
+}
+
+func TestSlices(t *testing.T) {
+	universal, err := Parse("darwin/universal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !universal.IsUniversal() {
+		t.Error("darwin/universal should be universal")
+	}
+	got := universal.Slices()
+	want := []Platform{{GOOS: "darwin", GOARCH: "amd64"}, {GOOS: "darwin", GOARCH: "arm64"}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Slices() = %v, want %v", got, want)
+	}
+
+	p := Platform{GOOS: "darwin", GOARCH: "arm64"}
+	if got := p.Slices(); len(got) != 1 || got[0] != p {
+		t.Errorf("Slices() of a single-arch platform = %v, want [%s]", got, p)
+	}
+}
+
 func TestAll(t *testing.T) {
 	all := All()
 
-	if len(all) != 6 {
-		t.Fatalf("All() returned %d platforms, want 6", len(all))
+	if len(all) != 22 {
+		t.Fatalf("All() returned %d platforms, want 22", len(all))
 	}
 
 	for i := 1; i < len(all); i++ {
 		a, b := all[i-1], all[i]
-		if a.GOOS > b.GOOS || (a.GOOS == b.GOOS && a.GOARCH > b.GOARCH) {
-			t.Errorf("All() not sorted at index %d: %s/%s comes before %s/%s",
-				i, b.GOOS, b.GOARCH, a.GOOS, a.GOARCH)
+		if a.GOOS > b.GOOS || (a.GOOS == b.GOOS && a.GOARCH > b.GOARCH) ||
+			(a.GOOS == b.GOOS && a.GOARCH == b.GOARCH && a.GOARM > b.GOARM) {
+			t.Errorf("All() not sorted at index %d: %s comes before %s", i, b, a)
 		}
 	}
 
 	for _, p := range all {
-		if _, err := Lookup(p.GOOS, p.GOARCH); err != nil {
-			t.Errorf("All() returned unresolvable platform %s/%s", p.GOOS, p.GOARCH)
+		if _, err := Lookup(p); err != nil {
+			t.Errorf("All() returned unresolvable platform %s", p)
 		}
 	}
 }
@@ -0,0 +1,63 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jacobarthurs/shipbin/internal/httpclient"
+)
+
+type EventKind int
+
+const (
+	EventInfo EventKind = iota
+	EventWarning
+	EventPublishing
+	EventPublished
+	EventSkipped
+)
+
+type Event struct {
+	Target  string
+	Kind    EventKind
+	Package string
+	Message string
+}
+
+type Reporter func(Event)
+
+func (r Reporter) Infof(target, format string, args ...any) {
+	r.emit(Event{Target: target, Kind: EventInfo, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r Reporter) Warnf(target, format string, args ...any) {
+	r.emit(Event{Target: target, Kind: EventWarning, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r Reporter) Publishing(target, pkg string, dryRun bool) {
+	verb := "publishing"
+	if dryRun {
+		verb = "would publish"
+	}
+	r.emit(Event{Target: target, Kind: EventPublishing, Package: pkg, Message: fmt.Sprintf("%s %s...", verb, pkg)})
+}
+
+func (r Reporter) Published(target, pkg string) {
+	r.emit(Event{Target: target, Kind: EventPublished, Package: pkg, Message: "published " + pkg})
+}
+
+func (r Reporter) Skipped(target, pkg string) {
+	r.emit(Event{Target: target, Kind: EventSkipped, Package: pkg, Message: pkg + " is already published, skipping"})
+}
+
+// ReportRetries returns a copy of ctx whose HTTP requests report their
+// retries to r as warnings.
+func (r Reporter) ReportRetries(ctx context.Context, target string) context.Context {
+	return httpclient.WithOnRetry(ctx, func(retry httpclient.Retry) { r.Warnf(target, "%s", retry) })
+}
+
+func (r Reporter) emit(e Event) {
+	if r != nil {
+		r(e)
+	}
+}
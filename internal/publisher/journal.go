@@ -0,0 +1,112 @@
+package publisher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Journal records every package uploaded for a release, with the digest of
+// its contents, so that an interrupted release resumes where it stopped.
+type Journal struct {
+	path string
+	mu   sync.Mutex
+	data journalData
+}
+
+type journalData struct {
+	Name      string                       `json:"name"`
+	Version   string                       `json:"version"`
+	Published map[string]map[string]string `json:"published"`
+}
+
+func OpenJournal(dir, name, version string) (*Journal, error) {
+	j := &Journal{
+		path: filepath.Join(dir, name+"-"+version+".json"),
+		data: journalData{Name: name, Version: version, Published: make(map[string]map[string]string)},
+	}
+	data, err := os.ReadFile(j.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	if err := json.Unmarshal(data, &j.data); err != nil {
+		return nil, fmt.Errorf("invalid journal %s: %w", j.path, err)
+	}
+	if j.data.Name != name || j.data.Version != version {
+		return nil, fmt.Errorf("journal %s is for %s %s, not %s %s", j.path, j.data.Name, j.data.Version, name, version)
+	}
+	if j.data.Published == nil {
+		j.data.Published = make(map[string]map[string]string)
+	}
+	return j, nil
+}
+
+func (j *Journal) Path() string {
+	return j.path
+}
+
+func (j *Journal) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	n := 0
+	for _, pkgs := range j.data.Published {
+		n += len(pkgs)
+	}
+	return n
+}
+
+// Digest returns the digest recorded for pkg, or "" if it isn't recorded.
+// A nil journal records nothing.
+func (j *Journal) Digest(target, pkg string) string {
+	if j == nil {
+		return ""
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.data.Published[target][pkg]
+}
+
+func (j *Journal) Record(target, pkg, digest string) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.data.Published[target] == nil {
+		j.data.Published[target] = make(map[string]string)
+	}
+	j.data.Published[target][pkg] = digest
+
+	data, err := json.MarshalIndent(j.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the journal once a release is complete, along with its
+// directory if nothing else is left in it.
+func (j *Journal) Remove() error {
+	if err := os.Remove(j.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	_ = os.Remove(filepath.Dir(j.path))
+	return nil
+}
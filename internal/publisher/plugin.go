@@ -0,0 +1,260 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	PluginPrefix   = "shipbin-publisher-"
+	pluginProtocol = 1
+
+	pluginStopGrace = 10 * time.Second
+)
+
+type Plugin struct {
+	name      string
+	path      string
+	options   map[string]any
+	release   *pluginRelease
+	state     json.RawMessage
+	outDir    string
+	existing  []string
+	packages  []string
+	published []string
+	skipped   []string
+}
+
+type pluginRequest struct {
+	Protocol int             `json:"protocol"`
+	Method   string          `json:"method"`
+	Release  *pluginRelease  `json:"release"`
+	Options  map[string]any  `json:"options,omitempty"`
+	State    json.RawMessage `json:"state,omitempty"`
+	OutDir   string          `json:"outDir,omitempty"`
+	Exists   []string        `json:"exists,omitempty"`
+}
+
+type pluginResponse struct {
+	Error     string          `json:"error"`
+	State     json.RawMessage `json:"state"`
+	Packages  []string        `json:"packages"`
+	Published []string        `json:"published"`
+	Exists    []string        `json:"exists"`
+	Skipped   []string        `json:"skipped"`
+	Files     []pluginPacked  `json:"files"`
+}
+
+type pluginPacked struct {
+	Package string `json:"package"`
+	Path    string `json:"path"`
+}
+
+type pluginRelease struct {
+	Name        string           `json:"name"`
+	Executables []string         `json:"executables"`
+	Version     string           `json:"version"`
+	Summary     string           `json:"summary,omitempty"`
+	License     string           `json:"license,omitempty"`
+	Readme      string           `json:"readme,omitempty"`
+	DryRun      bool             `json:"dryRun"`
+	Concurrency int              `json:"concurrency"`
+	Artifacts   []pluginArtifact `json:"artifacts"`
+}
+
+type pluginArtifact struct {
+	Platform    string             `json:"platform"`
+	GOOS        string             `json:"goos"`
+	GOARCH      string             `json:"goarch"`
+	Libc        string             `json:"libc,omitempty"`
+	MinOS       string             `json:"minOS,omitempty"`
+	Executables []pluginExecutable `json:"executables"`
+	Files       []pluginFile       `json:"files,omitempty"`
+}
+
+type pluginExecutable struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type pluginFile struct {
+	Src  string `json:"src"`
+	Dest string `json:"dest"`
+}
+
+func FindPlugin(name string, options map[string]any) (*Plugin, bool) {
+	path, err := exec.LookPath(PluginPrefix + name)
+	if err != nil {
+		return nil, false
+	}
+	return &Plugin{name: name, path: path, options: options}, true
+}
+
+func (p *Plugin) Name() string {
+	return p.name
+}
+
+func (p *Plugin) Build(ctx context.Context, rel Release) (func(), error) {
+	p.release = newPluginRelease(rel)
+	resp, err := p.call(ctx, "build")
+	if err != nil {
+		return nil, err
+	}
+	p.packages = resp.Packages
+	return func() { _, _ = p.call(context.Background(), "cleanup") }, nil
+}
+
+func (p *Plugin) Validate(ctx context.Context) error {
+	_, err := p.call(ctx, "validate")
+	return err
+}
+
+// Publish asks the plugin which packages already exist before publishing, and
+// passes them along so that it can leave them alone. Those it neither
+// publishes nor skips itself are reported as skipped.
+func (p *Plugin) Publish(ctx context.Context) error {
+	existing, err := p.Exists(ctx)
+	if err != nil {
+		return err
+	}
+	p.existing = existing
+	resp, err := p.call(ctx, "publish")
+	p.published = append(p.published, resp.Published...)
+	p.skipped = append(p.skipped, resp.Skipped...)
+	for _, pkg := range existing {
+		if slices.Contains(p.packages, pkg) && !slices.Contains(p.published, pkg) && !slices.Contains(p.skipped, pkg) {
+			p.skipped = append(p.skipped, pkg)
+		}
+	}
+	SortLike(p.skipped, p.packages)
+	return err
+}
+
+func (p *Plugin) Exists(ctx context.Context) ([]string, error) {
+	resp, err := p.call(ctx, "exists")
+	return resp.Exists, err
+}
+
+func (p *Plugin) Pack(ctx context.Context, dir string) ([]PackedFile, error) {
+	p.outDir = absPath(dir)
+	resp, err := p.call(ctx, "pack")
+	if err != nil {
+		return nil, err
+	}
+	var files []PackedFile
+	for _, f := range resp.Files {
+		files = append(files, PackedFile{Package: f.Package, Path: f.Path})
+	}
+	return files, nil
+}
+
+func (p *Plugin) Packages() []string {
+	return p.packages
+}
+
+func (p *Plugin) Published() []string {
+	return p.published
+}
+
+func (p *Plugin) Skipped() []string {
+	return p.skipped
+}
+
+func (p *Plugin) call(ctx context.Context, method string) (pluginResponse, error) {
+	req, err := json.Marshal(pluginRequest{
+		Protocol: pluginProtocol,
+		Method:   method,
+		Release:  p.release,
+		Options:  p.options,
+		State:    p.state,
+		OutDir:   p.outDir,
+		Exists:   p.existing,
+	})
+	if err != nil {
+		return pluginResponse{}, err
+	}
+
+	var stdout bytes.Buffer
+	// On cancellation the plugin gets an interrupt and some time to stop.
+	cmd := exec.CommandContext(ctx, p.path, method)
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = pluginStopGrace
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	runErr := cmd.Run()
+	if runErr != nil && ctx.Err() != nil {
+		runErr = ctx.Err()
+	}
+
+	var resp pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		if runErr != nil {
+			return pluginResponse{}, fmt.Errorf("%s: %s failed: %w", p.name, method, runErr)
+		}
+		return pluginResponse{}, fmt.Errorf("%s: invalid response to %s from %s: %w", p.name, method, p.path, err)
+	}
+	if len(resp.State) > 0 {
+		p.state = resp.State
+	}
+	if resp.Error != "" {
+		return resp, fmt.Errorf("%s: %s", p.name, resp.Error)
+	}
+	if runErr != nil {
+		return resp, fmt.Errorf("%s: %s failed: %w", p.name, method, runErr)
+	}
+	return resp, nil
+}
+
+func newPluginRelease(rel Release) *pluginRelease {
+	pr := &pluginRelease{
+		Name:        rel.Name,
+		Executables: rel.Executables,
+		Version:     rel.Version,
+		Summary:     rel.Summary,
+		License:     rel.License,
+		Readme:      absPath(rel.Readme),
+		DryRun:      rel.DryRun,
+		Concurrency: max(rel.Concurrency, 1),
+	}
+	for _, a := range rel.Artifacts {
+		pa := pluginArtifact{
+			Platform: a.Platform.String(),
+			GOOS:     a.Platform.GOOS,
+			GOARCH:   a.Platform.GOARCH,
+			Libc:     a.Platform.Libc,
+		}
+		if !a.MinOS.IsZero() {
+			pa.MinOS = a.MinOS.String()
+		}
+		for _, exe := range a.Executables {
+			name := exe.Name
+			if name == "" {
+				name = rel.Name
+			}
+			pa.Executables = append(pa.Executables, pluginExecutable{Name: name, Path: absPath(exe.Path)})
+		}
+		for _, f := range a.Files {
+			pa.Files = append(pa.Files, pluginFile{Src: absPath(f.Src), Dest: f.Dest})
+		}
+		pr.Artifacts = append(pr.Artifacts, pa)
+	}
+	return pr
+}
+
+func absPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
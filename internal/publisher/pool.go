@@ -0,0 +1,98 @@
+package publisher
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+)
+
+// Parallel runs task for every index below n on up to concurrency workers.
+// Each task reports through its own Reporter, and events are passed on to
+// report in index order, so output reads as if the tasks ran one by one. The
+// first failure cancels the context of the tasks still running, no new task
+// starts, and that failure is returned.
+func Parallel(ctx context.Context, concurrency, n int, report Reporter, task func(ctx context.Context, i int, report Reporter) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	taskCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		buffered = make([][]Event, n)
+		done     = make([]bool, n)
+		next     int
+		firstErr error
+	)
+	// The task at next reports live; the others buffer until it finishes.
+	advance := func() {
+		for next < n && done[next] {
+			next++
+			if next < n {
+				for _, e := range buffered[next] {
+					report.emit(e)
+				}
+				buffered[next] = nil
+			}
+		}
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(concurrency, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if taskCtx.Err() != nil {
+					continue
+				}
+				err := task(taskCtx, i, func(e Event) {
+					mu.Lock()
+					defer mu.Unlock()
+					if i == next {
+						report.emit(e)
+						return
+					}
+					buffered[i] = append(buffered[i], e)
+				})
+				mu.Lock()
+				done[i] = true
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				advance()
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := range n {
+		if taskCtx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for i := next; i < n; i++ {
+		for _, e := range buffered[i] {
+			report.emit(e)
+		}
+	}
+	if firstErr == nil {
+		return ctx.Err()
+	}
+	return firstErr
+}
+
+// SortLike sorts names, a subset of order, into the same order.
+func SortLike(names, order []string) {
+	slices.SortStableFunc(names, func(a, b string) int {
+		return cmp.Compare(slices.Index(order, a), slices.Index(order, b))
+	})
+}
@@ -0,0 +1,99 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallel_OrderedEvents(t *testing.T) {
+	var got []string
+	report := Reporter(func(e Event) { got = append(got, e.Message) })
+
+	var running, peak atomic.Int32
+	err := Parallel(t.Context(), 3, 6, report, func(_ context.Context, i int, report Reporter) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		report.Infof("test", "start %d", i)
+		time.Sleep(time.Duration(6-i) * 5 * time.Millisecond)
+		report.Infof("test", "end %d", i)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Parallel: %v", err)
+	}
+
+	var want []string
+	for i := range 6 {
+		want = append(want, fmt.Sprintf("start %d", i), fmt.Sprintf("end %d", i))
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	if p := peak.Load(); p > 3 || p < 2 {
+		t.Errorf("peak concurrency = %d, want up to 3", p)
+	}
+}
+
+func TestParallel_StopsAfterFailure(t *testing.T) {
+	var started atomic.Int32
+	boom := errors.New("boom")
+	err := Parallel(t.Context(), 2, 20, nil, func(ctx context.Context, i int, _ Reporter) error {
+		started.Add(1)
+		if i == 1 {
+			return boom
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(10 * time.Second):
+			t.Error("the failure didn't cancel the running task")
+			return nil
+		}
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("Parallel() = %v, want boom", err)
+	}
+	if n := started.Load(); n > 4 {
+		t.Errorf("%d tasks started after the failure, want the rest canceled", n)
+	}
+}
+
+func TestParallel_Sequential(t *testing.T) {
+	var order []int
+	err := Parallel(t.Context(), 0, 4, nil, func(_ context.Context, i int, _ Reporter) error {
+		order = append(order, i)
+		return nil
+	})
+	if err != nil || !slices.Equal(order, []int{0, 1, 2, 3}) {
+		t.Errorf("Parallel(0, ...) ran %v, %v, want 0..3 in order", order, err)
+	}
+}
+
+func TestParallel_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(t.Context())
+	var started atomic.Int32
+	err := Parallel(ctx, 1, 5, nil, func(_ context.Context, i int, _ Reporter) error {
+		started.Add(1)
+		if i == 1 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Parallel() = %v, want context.Canceled", err)
+	}
+	if n := started.Load(); n != 2 {
+		t.Errorf("%d tasks started, want none after the cancellation", n)
+	}
+}
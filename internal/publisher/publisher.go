@@ -0,0 +1,85 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jacobarthurs/shipbin/internal/config"
+)
+
+type Publisher interface {
+	Name() string
+	Build(ctx context.Context, rel Release) (func(), error)
+	Validate(ctx context.Context) error
+	Publish(ctx context.Context) error
+	Exists(ctx context.Context) ([]string, error)
+	Pack(ctx context.Context, dir string) ([]PackedFile, error)
+	Packages() []string
+	Published() []string
+	Skipped() []string
+}
+
+type Release struct {
+	Name        string
+	Executables []string
+	Version     string
+	Summary     string
+	License     string
+	Readme      string
+	Artifacts   []config.Artifact
+	DryRun      bool
+	Concurrency int
+	Retries     int
+	Timeout     time.Duration
+	Report      Reporter
+	Journal     *Journal
+}
+
+type PackedFile struct {
+	Package string
+	Path    string
+}
+
+type Factory func() (Publisher, error)
+
+type Registry struct {
+	factories     map[string]Factory
+	pluginOptions map[string]map[string]any
+}
+
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]Factory), pluginOptions: make(map[string]map[string]any)}
+}
+
+func (r *Registry) Register(name string, f Factory) {
+	r.factories[name] = f
+}
+
+func (r *Registry) ConfigurePlugin(name string, options map[string]any) {
+	r.pluginOptions[name] = options
+}
+
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (r *Registry) New(name string) (Publisher, error) {
+	if f, ok := r.factories[name]; ok {
+		return f()
+	}
+	if p, ok := FindPlugin(name, r.pluginOptions[name]); ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf(
+		"unknown target %q: must be one of %s, or a plugin named %s on PATH",
+		name, strings.Join(r.Names(), ", "), PluginPrefix+name,
+	)
+}
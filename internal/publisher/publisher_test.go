@@ -0,0 +1,208 @@
+package publisher
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jacobarthurs/shipbin/internal/config"
+	"github.com/jacobarthurs/shipbin/internal/platforms"
+)
+
+// fakePlugin installs a shell script that records each request and replies
+// with the canned response for its method.
+func fakePlugin(t *testing.T, name string, responses map[string]string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin scripts need a POSIX shell")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\ncat > \"" + dir + "/$1.json\"\ncase \"$1\" in\n"
+	for method, resp := range responses {
+		script += method + ") echo '" + resp + "' ;;\n"
+	}
+	script += "*) echo '{}' ;;\nesac\n"
+	if err := os.WriteFile(filepath.Join(dir, PluginPrefix+name), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return dir
+}
+
+func readRequest(t *testing.T, dir, method string) pluginRequest {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, method+".json"))
+	if err != nil {
+		t.Fatalf("plugin did not receive %s: %v", method, err)
+	}
+	var req pluginRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatalf("invalid %s request: %v", method, err)
+	}
+	return req
+}
+
+func TestRegistry(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register("npm", func() (Publisher, error) { return &Plugin{name: "npm"}, nil })
+
+	p, err := reg.New("npm")
+	if err != nil || p.Name() != "npm" {
+		t.Errorf("New(npm) = %v, %v", p, err)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	_, err = reg.New("artifactory")
+	if err == nil || !strings.Contains(err.Error(), "shipbin-publisher-artifactory") {
+		t.Errorf("New(artifactory) error = %v, want a hint about the plugin name", err)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	dir := fakePlugin(t, "store", map[string]string{
+		"build":    `{"packages":["mytool-linux-x64.tar.gz"],"state":{"dir":"/tmp/out"}}`,
+		"validate": `{}`,
+		"exists":   `{"exists":["mytool-linux-x64.tar.gz"]}`,
+		"publish":  `{"published":["mytool-linux-x64.tar.gz"]}`,
+	})
+
+	reg := NewRegistry()
+	reg.ConfigurePlugin("store", map[string]any{"bucket": "releases"})
+	p, err := reg.New("store")
+	if err != nil {
+		t.Fatalf("New(store): %v", err)
+	}
+
+	platform, _ := platforms.Parse("linux/amd64")
+	cleanup, err := p.Build(t.Context(), Release{
+		Name:    "mytool",
+		Version: "1.0.0",
+		Artifacts: []config.Artifact{{
+			Platform:    platform,
+			Executables: []config.Executable{{Path: "dist/mytool"}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if err := p.Validate(t.Context()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if got, err := p.Exists(t.Context()); err != nil || !slices.Equal(got, []string{"mytool-linux-x64.tar.gz"}) {
+		t.Errorf("Exists() = %v, %v", got, err)
+	}
+	if err := p.Publish(t.Context()); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	cleanup()
+
+	if got := p.Packages(); !slices.Equal(got, []string{"mytool-linux-x64.tar.gz"}) {
+		t.Errorf("Packages() = %v", got)
+	}
+	if got := p.Published(); !slices.Equal(got, []string{"mytool-linux-x64.tar.gz"}) {
+		t.Errorf("Published() = %v", got)
+	}
+
+	build := readRequest(t, dir, "build")
+	if build.Protocol != pluginProtocol || build.Method != "build" || build.Options["bucket"] != "releases" {
+		t.Errorf("build request = %+v", build)
+	}
+	a := build.Release.Artifacts[0]
+	if a.Platform != "linux/amd64" || a.Executables[0].Name != "mytool" || !filepath.IsAbs(a.Executables[0].Path) {
+		t.Errorf("build artifact = %+v", a)
+	}
+	if publish := readRequest(t, dir, "publish"); string(publish.State) != `{"dir":"/tmp/out"}` {
+		t.Errorf("publish state = %s, want the state returned by build", publish.State)
+	}
+	readRequest(t, dir, "cleanup")
+}
+
+func TestPlugin_SkipsExisting(t *testing.T) {
+	dir := fakePlugin(t, "store", map[string]string{
+		"build":   `{"packages":["a.tgz","b.tgz","c.tgz"]}`,
+		"exists":  `{"exists":["a.tgz","c.tgz"]}`,
+		"publish": `{"published":["b.tgz"]}`,
+	})
+	p, ok := FindPlugin("store", nil)
+	if !ok {
+		t.Fatal("FindPlugin(store) found nothing")
+	}
+	cleanup, err := p.Build(t.Context(), Release{Name: "mytool", Version: "1.0.0"})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	if err := p.Publish(t.Context()); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if got := p.Published(); !slices.Equal(got, []string{"b.tgz"}) {
+		t.Errorf("Published() = %v", got)
+	}
+	if got := p.Skipped(); !slices.Equal(got, []string{"a.tgz", "c.tgz"}) {
+		t.Errorf("Skipped() = %v, want the packages that already exist", got)
+	}
+	if publish := readRequest(t, dir, "publish"); !slices.Equal(publish.Exists, []string{"a.tgz", "c.tgz"}) {
+		t.Errorf("publish request exists = %v, want the result of exists", publish.Exists)
+	}
+}
+
+func TestPlugin_Error(t *testing.T) {
+	fakePlugin(t, "store", map[string]string{
+		"validate": `{"error":"STORE_TOKEN is not set"}`,
+	})
+	p, ok := FindPlugin("store", nil)
+	if !ok {
+		t.Fatal("FindPlugin(store) found nothing")
+	}
+	if err := p.Validate(t.Context()); err == nil || err.Error() != "store: STORE_TOKEN is not set" {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".shipbin")
+	j, err := OpenJournal(dir, "mytool", "1.0.0")
+	if err != nil {
+		t.Fatalf("OpenJournal: %v", err)
+	}
+	if j.Len() != 0 {
+		t.Errorf("Len() = %d for a new journal, want 0", j.Len())
+	}
+	if err := j.Record("npm", "@myorg/mytool-linux-x64", "sha512-abc"); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := j.Record("pypi", "mytool-1.0.0-py3-none-win_amd64.whl", "def"); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	j, err = OpenJournal(dir, "mytool", "1.0.0")
+	if err != nil {
+		t.Fatalf("OpenJournal: %v", err)
+	}
+	if j.Len() != 2 || j.Digest("npm", "@myorg/mytool-linux-x64") != "sha512-abc" || j.Digest("pypi", "mytool-1.0.0-py3-none-win_amd64.whl") != "def" {
+		t.Errorf("reopened journal = %+v", j.data)
+	}
+	if j.Digest("npm", "mytool") != "" {
+		t.Error("Digest() of an unrecorded package should be empty")
+	}
+	if _, err := OpenJournal(dir, "mytool", "2.0.0"); err != nil {
+		t.Errorf("OpenJournal() for another version = %v, want a new journal", err)
+	}
+
+	if err := j.Remove(); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("journal directory still exists after Remove: %v", err)
+	}
+
+	var none *Journal
+	if none.Digest("npm", "mytool") != "" || none.Record("npm", "mytool", "x") != nil {
+		t.Error("a nil journal should record nothing")
+	}
+}
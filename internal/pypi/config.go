@@ -3,11 +3,52 @@ package pypi
 import "github.com/jacobarthurs/shipbin/internal/config"
 
 type Config struct {
-	Name      string
-	Version   string
-	Artifacts []config.Artifact
-	Summary   string
-	License   string
-	Readme    string
-	DryRun    bool
+	Name        string
+	Executables []string
+	Version     string
+	Artifacts   []config.Artifact
+	Summary     string
+	License     string
+	Readme      string
+	DryRun      bool
+	Concurrency int
+
+	StrictVersion  bool
+	CompressedTags bool
+	SplitUniversal bool
+
+	Repository    string
+	RepositoryURL string
+	MaxFileSize   int64
+}
+
+func (c *Config) executables() []string {
+	if len(c.Executables) == 0 {
+		return []string{c.Name}
+	}
+	return c.Executables
+}
+
+func (c *Config) wheelArtifacts() []config.Artifact {
+	if !c.SplitUniversal {
+		return c.Artifacts
+	}
+	var result []config.Artifact
+	for _, a := range c.Artifacts {
+		result = append(result, a.Split()...)
+	}
+	return result
+}
+
+func (c *Config) version() (string, []string, error) {
+	return toPyPIVersion(c.Version, c.StrictVersion, !c.publicIndex())
+}
+
+// publicIndex reports whether the release goes to PyPI or TestPyPI, which
+// reject local versions.
+func (c *Config) publicIndex() bool {
+	if c.RepositoryURL != "" {
+		return sameURL(c.RepositoryURL, pypiUploadURL) || sameURL(c.RepositoryURL, testPyPIUploadURL)
+	}
+	return c.Repository == "" || c.Repository == "pypi" || c.Repository == "testpypi"
 }
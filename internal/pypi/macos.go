@@ -0,0 +1,26 @@
+package pypi
+
+import (
+	"fmt"
+
+	"github.com/jacobarthurs/shipbin/internal/config"
+)
+
+var macosFloor = map[string]config.OSVersion{
+	"arm64":      {Major: 11, Minor: 0},
+	"universal2": {Major: 11, Minor: 0},
+}
+
+func macosTag(v config.OSVersion, arch string) string {
+	if floor, ok := macosFloor[arch]; ok && v.Less(floor) {
+		v = floor
+	}
+	// pip only generates X_0 tags from macOS 11 on, so a minor version
+	// there would make the wheel uninstallable everywhere. Rounding down
+	// would let pip install it on releases the binary doesn't run on, so a
+	// 13.3 minimum is tagged 14_0.
+	if v.Major >= 11 && v.Minor > 0 {
+		v.Major, v.Minor = v.Major+1, 0
+	}
+	return fmt.Sprintf("macosx_%d_%d_%s", v.Major, v.Minor, arch)
+}
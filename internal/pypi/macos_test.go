@@ -0,0 +1,94 @@
+package pypi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jacobarthurs/shipbin/internal/config"
+)
+
+func TestMacOSTag(t *testing.T) {
+	tests := []struct {
+		version config.OSVersion
+		arch    string
+		want    string
+	}{
+		{config.OSVersion{Major: 10, Minor: 12}, "x86_64", "macosx_10_12_x86_64"},
+		{config.OSVersion{Major: 10, Minor: 15}, "x86_64", "macosx_10_15_x86_64"},
+		{config.OSVersion{Major: 11, Minor: 0}, "arm64", "macosx_11_0_arm64"},
+		{config.OSVersion{Major: 13, Minor: 3}, "arm64", "macosx_14_0_arm64"},
+		{config.OSVersion{Major: 11, Minor: 1}, "x86_64", "macosx_12_0_x86_64"},
+		{config.OSVersion{Major: 12, Minor: 0}, "x86_64", "macosx_12_0_x86_64"},
+		{config.OSVersion{Major: 10, Minor: 13}, "arm64", "macosx_11_0_arm64"},
+		{config.OSVersion{Major: 10, Minor: 13}, "universal2", "macosx_11_0_universal2"},
+		{config.OSVersion{Major: 12, Minor: 1}, "universal2", "macosx_13_0_universal2"},
+	}
+	for _, tt := range tests {
+		if got := macosTag(tt.version, tt.arch); got != tt.want {
+			t.Errorf("macosTag(%s, %s) = %q, want %q", tt.version, tt.arch, got, tt.want)
+		}
+	}
+}
+
+func TestBuildWheel_MacOSTag(t *testing.T) {
+	a := makeWheelArtifact(t, t.TempDir(), "darwin", "arm64")
+	a.MinOS = config.OSVersion{Major: 14, Minor: 0}
+
+	wf, err := buildWheel(&Config{Name: "mytool", Version: "1.0.0"}, a, t.TempDir())
+	if err != nil {
+		t.Fatalf("buildWheel: %v", err)
+	}
+	if want := "mytool-1.0.0-py3-none-macosx_14_0_arm64.whl"; wf.filename != want {
+		t.Errorf("filename = %q, want %q", wf.filename, want)
+	}
+
+	a.MinOS = config.OSVersion{}
+	wf, err = buildWheel(&Config{Name: "mytool", Version: "1.0.0"}, a, t.TempDir())
+	if err != nil {
+		t.Fatalf("buildWheel: %v", err)
+	}
+	if want := "mytool-1.0.0-py3-none-macosx_11_0_arm64.whl"; wf.filename != want {
+		t.Errorf("filename without a known minimum = %q, want %q", wf.filename, want)
+	}
+}
+
+func TestWheelArtifacts_Universal(t *testing.T) {
+	dir := t.TempDir()
+	universal := makeWheelArtifact(t, dir, "darwin", "universal")
+	linux := makeWheelArtifact(t, dir, "linux", "amd64")
+
+	cfg := &Config{Name: "mytool", Version: "1.0.0", Artifacts: []config.Artifact{universal, linux}}
+	var got []string
+	for _, a := range cfg.wheelArtifacts() {
+		wf, err := buildWheel(cfg, a, t.TempDir())
+		if err != nil {
+			t.Fatalf("buildWheel(%s): %v", a.Platform, err)
+		}
+		got = append(got, wf.filename)
+	}
+	want := []string{
+		"mytool-1.0.0-py3-none-macosx_11_0_universal2.whl",
+		"mytool-1.0.0-py3-none-manylinux_2_17_x86_64.manylinux2014_x86_64.whl",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("wheels = %v, want %v", got, want)
+	}
+
+	cfg.SplitUniversal = true
+	got = nil
+	for _, a := range cfg.wheelArtifacts() {
+		wf, err := buildWheel(cfg, a, t.TempDir())
+		if err != nil {
+			t.Fatalf("buildWheel(%s): %v", a.Platform, err)
+		}
+		got = append(got, wf.filename)
+	}
+	want = []string{
+		"mytool-1.0.0-py3-none-macosx_10_12_x86_64.whl",
+		"mytool-1.0.0-py3-none-macosx_11_0_arm64.whl",
+		"mytool-1.0.0-py3-none-manylinux_2_17_x86_64.manylinux2014_x86_64.whl",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("split wheels = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,248 @@
+package pypi
+
+import (
+	"bytes"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jacobarthurs/shipbin/internal/config"
+)
+
+type glibcVersion struct {
+	major, minor int
+}
+
+func (v glibcVersion) less(o glibcVersion) bool {
+	return v.major < o.major || (v.major == o.major && v.minor < o.minor)
+}
+
+var manylinuxFloor = map[string]glibcVersion{
+	"x86_64":  {2, 5},
+	"i686":    {2, 5},
+	"aarch64": {2, 17},
+	"armv7l":  {2, 17},
+	"ppc64le": {2, 17},
+	"s390x":   {2, 17},
+}
+
+var legacyManylinux = []struct {
+	version glibcVersion
+	name    string
+	arches  []string
+}{
+	{glibcVersion{2, 5}, "manylinux1", []string{"x86_64", "i686"}},
+	{glibcVersion{2, 12}, "manylinux2010", []string{"x86_64", "i686"}},
+	{glibcVersion{2, 17}, "manylinux2014", []string{"x86_64", "i686", "aarch64", "armv7l", "ppc64le", "s390x"}},
+}
+
+var manylinuxLibraries = []string{
+	"libgcc_s.so.1",
+	"libstdc++.so.6",
+	"libm.so.6",
+	"libdl.so.2",
+	"librt.so.1",
+	"libc.so.6",
+	"libnsl.so.1",
+	"libutil.so.1",
+	"libpthread.so.0",
+	"libresolv.so.2",
+	"libX11.so.6",
+	"libXext.so.6",
+	"libXrender.so.1",
+	"libICE.so.6",
+	"libSM.so.6",
+	"libGL.so.1",
+	"libgobject-2.0.so.0",
+	"libgthread-2.0.so.0",
+	"libglib-2.0.so.0",
+}
+
+var errNotELF = errors.New("not an ELF file")
+
+func manylinuxTag(a config.Artifact, arch string, compressed bool) (string, error) {
+	floor, ok := manylinuxFloor[arch]
+	if !ok {
+		return "", fmt.Errorf("no manylinux policy for %s", arch)
+	}
+
+	bundled := make(map[string]bool)
+	var libs []config.ExtraFile
+	for _, f := range a.Files {
+		if strings.HasPrefix(f.Dest, "bin/") && strings.Contains(path.Base(f.Dest), ".so") {
+			bundled[path.Base(f.Dest)] = true
+			libs = append(libs, f)
+		}
+	}
+
+	required := floor
+	var errs []error
+	check := func(name, file string) error {
+		needed, glibc, err := scanELF(file)
+		if err != nil {
+			return err
+		}
+		if required.less(glibc) {
+			required = glibc
+		}
+		for _, lib := range needed {
+			if !slices.Contains(manylinuxLibraries, lib) && !isDynamicLoader(lib) && !bundled[lib] {
+				errs = append(errs, fmt.Errorf("%s links against %s, which is outside the manylinux policy (bundle it with --include or link it statically)", name, lib))
+			}
+		}
+		return nil
+	}
+
+	for _, exe := range a.Executables {
+		if err := check(filepath.Base(exe.Path), exe.Path); err != nil {
+			return "", fmt.Errorf("failed to inspect %s: %w", exe.Path, err)
+		}
+	}
+	for _, f := range libs {
+		if err := check(f.Dest, f.Src); err != nil && !errors.Is(err, errNotELF) {
+			return "", fmt.Errorf("failed to inspect %s: %w", f.Src, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return "", err
+	}
+
+	return manylinuxTagSet(required, arch, compressed), nil
+}
+
+func manylinuxTagSet(v glibcVersion, arch string, compressed bool) string {
+	var tags []string
+	add := func(v glibcVersion) {
+		tags = append(tags, fmt.Sprintf("manylinux_%d_%d_%s", v.major, v.minor, arch))
+		for _, legacy := range legacyManylinux {
+			if legacy.version == v && slices.Contains(legacy.arches, arch) {
+				tags = append(tags, legacy.name+"_"+arch)
+			}
+		}
+	}
+
+	add(v)
+	if compressed {
+		for _, legacy := range legacyManylinux {
+			if v.less(legacy.version) && slices.Contains(legacy.arches, arch) {
+				add(legacy.version)
+			}
+		}
+	}
+	return strings.Join(tags, ".")
+}
+
+func isDynamicLoader(lib string) bool {
+	return strings.HasPrefix(lib, "ld-linux") || strings.HasPrefix(lib, "ld64.so")
+}
+
+func scanELF(file string) ([]string, glibcVersion, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, glibcVersion{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	magic := make([]byte, len(elf.ELFMAG))
+	if _, err := io.ReadFull(f, magic); err != nil || !bytes.Equal(magic, []byte(elf.ELFMAG)) {
+		return nil, glibcVersion{}, errNotELF
+	}
+
+	ef, err := elf.NewFile(f)
+	if err != nil {
+		return nil, glibcVersion{}, err
+	}
+
+	needed, err := ef.ImportedLibraries()
+	if err != nil {
+		return nil, glibcVersion{}, err
+	}
+
+	deps, err := versionNeeds(ef)
+	if err != nil {
+		return nil, glibcVersion{}, err
+	}
+	var glibc glibcVersion
+	for _, dep := range deps {
+		if v, ok := parseGlibcVersion(dep); ok && glibc.less(v) {
+			glibc = v
+		}
+	}
+	return needed, glibc, nil
+}
+
+func parseGlibcVersion(dep string) (glibcVersion, bool) {
+	rest, ok := strings.CutPrefix(dep, "GLIBC_")
+	if !ok {
+		return glibcVersion{}, false
+	}
+	majorStr, minorStr, _ := strings.Cut(rest, ".")
+	minorStr, _, _ = strings.Cut(minorStr, ".")
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return glibcVersion{}, false
+	}
+	minor, err := strconv.Atoi(minorStr)
+	if err != nil {
+		return glibcVersion{}, false
+	}
+	return glibcVersion{major, minor}, true
+}
+
+func versionNeeds(ef *elf.File) ([]string, error) {
+	vn := ef.SectionByType(elf.SHT_GNU_VERNEED)
+	if vn == nil {
+		return nil, nil
+	}
+	if int(vn.Link) >= len(ef.Sections) {
+		return nil, fmt.Errorf("invalid string table index %d for %s", vn.Link, vn.Name)
+	}
+	d, err := vn.Data()
+	if err != nil {
+		return nil, err
+	}
+	str, err := ef.Sections[vn.Link].Data()
+	if err != nil {
+		return nil, err
+	}
+
+	var deps []string
+	for i := 0; i+16 <= len(d); {
+		cnt := int(ef.ByteOrder.Uint16(d[i+2:]))
+		aux := int(ef.ByteOrder.Uint32(d[i+8:]))
+		next := int(ef.ByteOrder.Uint32(d[i+12:]))
+
+		for j, c := i+aux, 0; c < cnt && j+16 <= len(d); c++ {
+			deps = append(deps, cString(str, int(ef.ByteOrder.Uint32(d[j+8:]))))
+			auxNext := int(ef.ByteOrder.Uint32(d[j+12:]))
+			if auxNext == 0 {
+				break
+			}
+			j += auxNext
+		}
+
+		if next == 0 {
+			break
+		}
+		i += next
+	}
+	return deps, nil
+}
+
+func cString(b []byte, off int) string {
+	if off < 0 || off >= len(b) {
+		return ""
+	}
+	s := b[off:]
+	if end := bytes.IndexByte(s, 0); end >= 0 {
+		s = s[:end]
+	}
+	return string(s)
+}
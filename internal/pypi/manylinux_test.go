@@ -0,0 +1,234 @@
+package pypi
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jacobarthurs/shipbin/internal/config"
+	"github.com/jacobarthurs/shipbin/internal/platforms"
+)
+
+type elfStrtab struct {
+	data []byte
+}
+
+func (s *elfStrtab) add(str string) uint32 {
+	if len(s.data) == 0 {
+		s.data = []byte{0}
+	}
+	off := uint32(len(s.data))
+	s.data = append(s.data, str...)
+	s.data = append(s.data, 0)
+	return off
+}
+
+func fakeLinkedELF(t *testing.T, needed []string, glibc ...string) []byte {
+	t.Helper()
+	var dynstr elfStrtab
+	var dynamic bytes.Buffer
+	for _, lib := range needed {
+		mustWrite(t, &dynamic, elf.Dyn64{Tag: int64(elf.DT_NEEDED), Val: uint64(dynstr.add(lib))})
+	}
+	mustWrite(t, &dynamic, elf.Dyn64{Tag: int64(elf.DT_NULL)})
+
+	var verneed bytes.Buffer
+	if len(glibc) > 0 {
+		mustWrite(t, &verneed, struct {
+			Version, Cnt    uint16
+			File, Aux, Next uint32
+		}{1, uint16(len(glibc)), dynstr.add("libc.so.6"), 16, 0})
+		for i, v := range glibc {
+			next := uint32(16)
+			if i == len(glibc)-1 {
+				next = 0
+			}
+			mustWrite(t, &verneed, struct {
+				Hash         uint32
+				Flags, Other uint16
+				Name, Next   uint32
+			}{0, 0, uint16(i + 2), dynstr.add(v), next})
+		}
+	}
+
+	var shstrtab elfStrtab
+	type section struct {
+		name  string
+		typ   elf.SectionType
+		link  uint32
+		info  uint32
+		data  []byte
+		entsz uint64
+	}
+	sections := []section{
+		{name: ".dynstr", typ: elf.SHT_STRTAB, data: dynstr.data},
+		{name: ".dynamic", typ: elf.SHT_DYNAMIC, link: 1, data: dynamic.Bytes(), entsz: 16},
+	}
+	if verneed.Len() > 0 {
+		sections = append(sections, section{name: ".gnu.version_r", typ: elf.SHT_GNU_VERNEED, link: 1, info: 1, data: verneed.Bytes()})
+	}
+	names := make([]uint32, len(sections))
+	for i, s := range sections {
+		names[i] = shstrtab.add(s.name)
+	}
+	shstrndx := shstrtab.add(".shstrtab")
+	sections = append(sections, section{typ: elf.SHT_STRTAB, data: shstrtab.data})
+	names = append(names, shstrndx)
+
+	var body bytes.Buffer
+	offsets := make([]uint64, len(sections))
+	for i, s := range sections {
+		offsets[i] = uint64(64 + body.Len())
+		body.Write(s.data)
+	}
+
+	var ident [elf.EI_NIDENT]byte
+	copy(ident[:], elf.ELFMAG)
+	ident[elf.EI_CLASS] = byte(elf.ELFCLASS64)
+	ident[elf.EI_DATA] = byte(elf.ELFDATA2LSB)
+	ident[elf.EI_VERSION] = byte(elf.EV_CURRENT)
+
+	var buf bytes.Buffer
+	mustWrite(t, &buf, elf.Header64{
+		Ident: ident, Type: uint16(elf.ET_DYN), Machine: uint16(elf.EM_X86_64), Version: uint32(elf.EV_CURRENT),
+		Shoff: uint64(64 + body.Len()), Ehsize: 64, Phentsize: 56, Shentsize: 64,
+		Shnum: uint16(len(sections) + 1), Shstrndx: uint16(len(sections)),
+	})
+	buf.Write(body.Bytes())
+	mustWrite(t, &buf, elf.Section64{})
+	for i, s := range sections {
+		mustWrite(t, &buf, elf.Section64{
+			Name: names[i], Type: uint32(s.typ), Off: offsets[i], Size: uint64(len(s.data)),
+			Link: s.link, Info: s.info, Addralign: 1, Entsize: s.entsz,
+		})
+	}
+	return buf.Bytes()
+}
+
+func mustWrite(t *testing.T, buf *bytes.Buffer, v any) {
+	t.Helper()
+	if err := binary.Write(buf, binary.LittleEndian, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func linkedArtifact(t *testing.T, data []byte, files ...config.ExtraFile) config.Artifact {
+	t.Helper()
+	p := platforms.Platform{GOOS: "linux", GOARCH: "amd64"}
+	m, err := platforms.Lookup(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "mytool")
+	if err := os.WriteFile(path, data, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return config.Artifact{Platform: p, Mapping: m, Executables: []config.Executable{{Path: path}}, Files: files}
+}
+
+func TestManylinuxTag(t *testing.T) {
+	tests := []struct {
+		name   string
+		needed []string
+		glibc  []string
+		want   string
+	}{
+		{"static", nil, nil, "manylinux_2_5_x86_64.manylinux1_x86_64"},
+		{"old glibc", []string{"libc.so.6"}, []string{"GLIBC_2.2.5", "GLIBC_2.3"}, "manylinux_2_5_x86_64.manylinux1_x86_64"},
+		{"glibc 2.17", []string{"libc.so.6", "libpthread.so.0"}, []string{"GLIBC_2.2.5", "GLIBC_2.17"}, "manylinux_2_17_x86_64.manylinux2014_x86_64"},
+		{"glibc 2.28", []string{"libc.so.6", "libm.so.6"}, []string{"GLIBC_2.14", "GLIBC_2.28", "GLIBC_2.3.4"}, "manylinux_2_28_x86_64"},
+		{"loader", []string{"libc.so.6", "ld-linux-x86-64.so.2"}, []string{"GLIBC_2.34"}, "manylinux_2_34_x86_64"},
+		{"private versions", []string{"libc.so.6"}, []string{"GLIBC_PRIVATE", "GLIBC_2.12"}, "manylinux_2_12_x86_64.manylinux2010_x86_64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := linkedArtifact(t, fakeLinkedELF(t, tt.needed, tt.glibc...))
+			got, err := manylinuxTag(a, "x86_64", false)
+			if err != nil {
+				t.Fatalf("manylinuxTag: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("manylinuxTag() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManylinuxTag_DisallowedLibrary(t *testing.T) {
+	a := linkedArtifact(t, fakeLinkedELF(t, []string{"libc.so.6", "libssl.so.3", "libz.so.1"}, "GLIBC_2.17"))
+	_, err := manylinuxTag(a, "x86_64", false)
+	if err == nil {
+		t.Fatal("expected error for library outside the manylinux policy, got nil")
+	}
+	checkContains(t, err.Error(), "mytool links against libssl.so.3", "mytool links against libz.so.1", "--include")
+}
+
+func TestManylinuxTag_BundledLibrary(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "libfoo.so.1")
+	if err := os.WriteFile(lib, fakeLinkedELF(t, []string{"libc.so.6"}, "GLIBC_2.31"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a := linkedArtifact(t, fakeLinkedELF(t, []string{"libc.so.6", "libfoo.so.1"}, "GLIBC_2.17"),
+		config.ExtraFile{Src: lib, Dest: "bin/libfoo.so.1"})
+
+	got, err := manylinuxTag(a, "x86_64", false)
+	if err != nil {
+		t.Fatalf("manylinuxTag: %v", err)
+	}
+	if want := "manylinux_2_31_x86_64"; got != want {
+		t.Errorf("manylinuxTag() = %q, want %q (bundled libraries count towards the glibc requirement)", got, want)
+	}
+}
+
+func TestManylinuxTag_NotELF(t *testing.T) {
+	a := linkedArtifact(t, []byte("#!/bin/sh\n"))
+	if _, err := manylinuxTag(a, "x86_64", false); err == nil || !strings.Contains(err.Error(), errNotELF.Error()) {
+		t.Errorf("manylinuxTag() error = %v, want %v", err, errNotELF)
+	}
+}
+
+func TestManylinuxTagSet(t *testing.T) {
+	tests := []struct {
+		version    glibcVersion
+		arch       string
+		compressed bool
+		want       string
+	}{
+		{glibcVersion{2, 5}, "x86_64", false, "manylinux_2_5_x86_64.manylinux1_x86_64"},
+		{glibcVersion{2, 5}, "x86_64", true, "manylinux_2_5_x86_64.manylinux1_x86_64.manylinux_2_12_x86_64.manylinux2010_x86_64.manylinux_2_17_x86_64.manylinux2014_x86_64"},
+		{glibcVersion{2, 17}, "aarch64", false, "manylinux_2_17_aarch64.manylinux2014_aarch64"},
+		{glibcVersion{2, 17}, "aarch64", true, "manylinux_2_17_aarch64.manylinux2014_aarch64"},
+		{glibcVersion{2, 14}, "i686", true, "manylinux_2_14_i686.manylinux_2_17_i686.manylinux2014_i686"},
+		{glibcVersion{2, 28}, "x86_64", true, "manylinux_2_28_x86_64"},
+	}
+	for _, tt := range tests {
+		if got := manylinuxTagSet(tt.version, tt.arch, tt.compressed); got != tt.want {
+			t.Errorf("manylinuxTagSet(%v, %s, %v) = %q, want %q", tt.version, tt.arch, tt.compressed, got, tt.want)
+		}
+	}
+}
+
+func TestBuildWheel_ManylinuxTag(t *testing.T) {
+	a := linkedArtifact(t, fakeLinkedELF(t, []string{"libc.so.6"}, "GLIBC_2.28"))
+
+	wf, err := buildWheel(&Config{Name: "mytool", Version: "1.0.0"}, a, t.TempDir())
+	if err != nil {
+		t.Fatalf("buildWheel: %v", err)
+	}
+	if want := "mytool-1.0.0-py3-none-manylinux_2_28_x86_64.whl"; wf.filename != want {
+		t.Errorf("filename = %q, want %q", wf.filename, want)
+	}
+
+	wf, err = buildWheel(&Config{Name: "mytool", Version: "1.0.0", CompressedTags: true}, linkedArtifact(t, fakeLinkedELF(t, nil)), t.TempDir())
+	if err != nil {
+		t.Fatalf("buildWheel: %v", err)
+	}
+	if !strings.Contains(wf.filename, "manylinux1_x86_64.manylinux_2_12_x86_64.manylinux2010_x86_64.manylinux_2_17_x86_64.manylinux2014_x86_64.whl") {
+		t.Errorf("compressed filename = %q, want every legacy policy", wf.filename)
+	}
+}
@@ -2,41 +2,31 @@ package pypi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+
+	"github.com/jacobarthurs/shipbin/internal/httpclient"
 )
 
 var pypiMintTokenURL = "https://pypi.org/oidc/mint-token/"
 
-func mintToken() (string, error) {
-	if token := os.Getenv("PYPI_TOKEN"); token != "" {
-		fmt.Println("pypi: authenticating with PYPI_TOKEN")
-		return token, nil
-	}
-
-	requestURL := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
-	requestToken := os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
-
-	if requestURL == "" || requestToken == "" {
-		return "", fmt.Errorf(
-			"pypi: no credentials found\n" +
-				"set PYPI_TOKEN for local publishing, or\n" +
-				"ensure your workflow has 'id-token: write' permission and\n" +
-				"a trusted publisher is registered at https://pypi.org/manage/account/publishing/",
-		)
+func mintToken(ctx context.Context, repo repository) (string, error) {
+	requestURL, requestToken, err := oidcRequest(repo)
+	if err != nil {
+		return "", err
 	}
 
-	fmt.Println("pypi: authenticating with OIDC trusted publisher")
-	oidcToken, err := requestOIDCToken(requestURL, requestToken)
+	oidcToken, err := requestOIDCToken(ctx, repo.client, requestURL, requestToken, repo.audience)
 	if err != nil {
 		return "", fmt.Errorf("pypi: failed to request OIDC token: %w", err)
 	}
 
-	uploadToken, err := exchangeForUploadToken(oidcToken)
+	uploadToken, err := exchangeForUploadToken(ctx, repo.client, repo.mintTokenURL, oidcToken)
 	if err != nil {
 		return "", fmt.Errorf("pypi: failed to mint PyPI upload token: %w", err)
 	}
@@ -44,23 +34,40 @@ func mintToken() (string, error) {
 	return uploadToken, nil
 }
 
-func requestOIDCToken(requestURL, requestToken string) (string, error) {
+// oidcRequest returns where GitHub Actions hands out OIDC tokens, which is
+// only set for jobs with the id-token: write permission.
+func oidcRequest(repo repository) (requestURL, requestToken string, err error) {
+	requestURL = os.Getenv("ACTIONS_ID_TOKEN_REQUEST_URL")
+	requestToken = os.Getenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN")
+	if requestURL == "" || requestToken == "" {
+		return "", "", fmt.Errorf(
+			"pypi: no credentials found\n"+
+				"set PYPI_TOKEN for local publishing, or\n"+
+				"ensure your workflow has 'id-token: write' permission and\n"+
+				"a trusted publisher is registered at %s",
+			publishingURL(repo.mintTokenURL),
+		)
+	}
+	return requestURL, requestToken, nil
+}
+
+func requestOIDCToken(ctx context.Context, client *httpclient.Client, requestURL, requestToken, audience string) (string, error) {
 	u, err := url.Parse(requestURL)
 	if err != nil {
 		return "", fmt.Errorf("invalid OIDC request URL: %w", err)
 	}
 	q := u.Query()
-	q.Set("audience", "pypi")
+	q.Set("audience", audience)
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
 	if err != nil {
 		return "", err
 	}
 	req.Header.Set("Authorization", "Bearer "+requestToken)
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -84,7 +91,7 @@ func requestOIDCToken(requestURL, requestToken string) (string, error) {
 	return result.Value, nil
 }
 
-func exchangeForUploadToken(oidcToken string) (string, error) {
+func exchangeForUploadToken(ctx context.Context, client *httpclient.Client, mintTokenURL, oidcToken string) (string, error) {
 	payload, err := json.Marshal(struct {
 		Token string `json:"token"`
 	}{Token: oidcToken})
@@ -92,7 +99,13 @@ func exchangeForUploadToken(oidcToken string) (string, error) {
 		return "", err
 	}
 
-	resp, err := http.Post(pypiMintTokenURL, "application/json", bytes.NewReader(payload))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, mintTokenURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -115,3 +128,11 @@ func exchangeForUploadToken(oidcToken string) (string, error) {
 
 	return result.Token, nil
 }
+
+func publishingURL(mintTokenURL string) string {
+	u, err := url.Parse(mintTokenURL)
+	if err != nil {
+		return "https://pypi.org/manage/account/publishing/"
+	}
+	return u.Scheme + "://" + u.Host + "/manage/account/publishing/"
+}
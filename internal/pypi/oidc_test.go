@@ -22,7 +22,7 @@ func TestRequestOIDCToken_Success(t *testing.T) {
 	}))
 	defer server.Close()
 
-	token, err := requestOIDCToken(server.URL, "test-bearer-token")
+	token, err := requestOIDCToken(t.Context(), nil, server.URL, "test-bearer-token", "pypi")
 	if err != nil {
 		t.Fatalf("requestOIDCToken: %v", err)
 	}
@@ -37,7 +37,7 @@ func TestRequestOIDCToken_NonOKStatus(t *testing.T) {
 	}))
 	defer server.Close()
 
-	_, err := requestOIDCToken(server.URL, "bad-token")
+	_, err := requestOIDCToken(t.Context(), nil, server.URL, "bad-token", "pypi")
 	if err == nil {
 		t.Fatal("expected error for non-200 status, got nil")
 	}
@@ -50,14 +50,14 @@ func TestRequestOIDCToken_EmptyTokenValue(t *testing.T) {
 	}))
 	defer server.Close()
 
-	_, err := requestOIDCToken(server.URL, "token")
+	_, err := requestOIDCToken(t.Context(), nil, server.URL, "token", "pypi")
 	if err == nil {
 		t.Fatal("expected error for empty token value, got nil")
 	}
 }
 
 func TestRequestOIDCToken_InvalidURL(t *testing.T) {
-	_, err := requestOIDCToken("://not-a-valid-url", "token")
+	_, err := requestOIDCToken(t.Context(), nil, "://not-a-valid-url", "token", "pypi")
 	if err == nil {
 		t.Fatal("expected error for invalid URL, got nil")
 	}
@@ -72,7 +72,7 @@ func TestRequestOIDCToken_AppendAudienceToExistingQuery(t *testing.T) {
 	}))
 	defer server.Close()
 
-	_, err := requestOIDCToken(server.URL+"?existing=1", "token")
+	_, err := requestOIDCToken(t.Context(), nil, server.URL+"?existing=1", "token", "pypi")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -103,11 +103,7 @@ func TestExchangeForUploadToken_Success(t *testing.T) {
 	}))
 	defer server.Close()
 
-	orig := pypiMintTokenURL
-	pypiMintTokenURL = server.URL
-	defer func() { pypiMintTokenURL = orig }()
-
-	token, err := exchangeForUploadToken("oidc-jwt")
+	token, err := exchangeForUploadToken(t.Context(), nil, server.URL, "oidc-jwt")
 	if err != nil {
 		t.Fatalf("exchangeForUploadToken: %v", err)
 	}
@@ -122,11 +118,7 @@ func TestExchangeForUploadToken_NonOKStatus(t *testing.T) {
 	}))
 	defer server.Close()
 
-	orig := pypiMintTokenURL
-	pypiMintTokenURL = server.URL
-	defer func() { pypiMintTokenURL = orig }()
-
-	_, err := exchangeForUploadToken("oidc-jwt")
+	_, err := exchangeForUploadToken(t.Context(), nil, server.URL, "oidc-jwt")
 	if err == nil {
 		t.Fatal("expected error for non-200 status, got nil")
 	}
@@ -139,11 +131,7 @@ func TestExchangeForUploadToken_EmptyToken(t *testing.T) {
 	}))
 	defer server.Close()
 
-	orig := pypiMintTokenURL
-	pypiMintTokenURL = server.URL
-	defer func() { pypiMintTokenURL = orig }()
-
-	_, err := exchangeForUploadToken("oidc-jwt")
+	_, err := exchangeForUploadToken(t.Context(), nil, server.URL, "oidc-jwt")
 	if err == nil {
 		t.Fatal("expected error for empty upload token, got nil")
 	}
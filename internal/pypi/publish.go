@@ -1,58 +1,293 @@
 package pypi
 
 import (
-	"bytes"
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jacobarthurs/shipbin/internal/httpclient"
+	"github.com/jacobarthurs/shipbin/internal/publisher"
 )
 
 var pypiUploadURL = "https://upload.pypi.org/legacy/"
 
-func Publish(cfg *Config) error {
+type Publisher struct {
+	cfg       *Config
+	version   string
+	wheels    []wheelFile
+	repo      repository
+	creds     credentials
+	client    *httpclient.Client
+	report    publisher.Reporter
+	journal   *publisher.Journal
+	mu        sync.Mutex
+	published []string
+	skipped   []string
+}
+
+func NewPublisher(cfg *Config) *Publisher {
+	return &Publisher{cfg: cfg}
+}
+
+func (p *Publisher) Name() string {
+	return "pypi"
+}
+
+func (p *Publisher) Build(_ context.Context, rel publisher.Release) (func(), error) {
+	cfg := p.cfg
+	cfg.Name = rel.Name
+	cfg.Executables = rel.Executables
+	cfg.Version = rel.Version
+	cfg.Summary = rel.Summary
+	cfg.License = rel.License
+	cfg.Readme = rel.Readme
+	cfg.Artifacts = rel.Artifacts
+	cfg.DryRun = rel.DryRun
+	cfg.Concurrency = rel.Concurrency
+	p.report = rel.Report
+	p.client = httpclient.New(rel.Retries, rel.Timeout, nil)
+	p.journal = rel.Journal
+
+	version, lossy, err := cfg.version()
+	if err != nil {
+		return nil, fmt.Errorf("pypi: %w", err)
+	}
+	if version != cfg.Version {
+		p.report.Infof("pypi", "using PEP 440 version %s (from %s)", version, cfg.Version)
+	}
+	for _, note := range lossy {
+		p.report.Warnf("pypi", "%s (use --strict-version to refuse lossy conversions)", note)
+	}
+
+	if _, _, err := readReadme(cfg.Readme); err != nil {
+		return nil, fmt.Errorf("pypi: failed to read readme: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "shipbin-pypi-*")
+	if err != nil {
+		return nil, fmt.Errorf("pypi: %w", err)
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+
+	p.version = version
+	var errs []error
+	for _, a := range cfg.wheelArtifacts() {
+		if a.Mapping.PyPI.WheelTag == "" {
+			p.report.Infof("pypi", "skipping %s (no PyPI wheel tag)", a.Platform)
+			continue
+		}
+		if strings.HasPrefix(a.Mapping.PyPI.WheelTag, "linux_") && cfg.publicIndex() {
+			p.report.Warnf("pypi", "skipping %s (PyPI doesn't accept %s wheels: publish them to another repository)", a.Platform, a.Mapping.PyPI.WheelTag)
+			continue
+		}
+		w, err := buildWheel(cfg, a, dir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("pypi: failed to build wheel for %s: %w", a.Platform, err))
+			continue
+		}
+		p.wheels = append(p.wheels, w)
+	}
+	if len(errs) > 0 {
+		cleanup()
+		return nil, errors.Join(errs...)
+	}
+	return cleanup, nil
+}
+
+func (p *Publisher) Validate(ctx context.Context) error {
+	repo, err := resolveRepository(p.cfg.Repository, p.cfg.RepositoryURL)
+	repo.client = p.client
+	if p.cfg.MaxFileSize > 0 {
+		repo.maxFileSize = p.cfg.MaxFileSize
+	}
+	errs := []error{p.validateWheels(repo), err}
+	if err == nil && !p.cfg.DryRun {
+		p.creds, err = repo.credentials(p.report)
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	p.repo = repo
+	return nil
+}
+
+func (p *Publisher) Publish(ctx context.Context) error {
 	verb := "publishing"
-	if cfg.DryRun {
+	if p.cfg.DryRun {
 		verb = "would publish"
 	}
 
-	fmt.Printf("pypi: %s version %s\n", verb, cfg.Version)
+	p.report.Infof("pypi", "%s version %s", verb, p.version)
+	if p.repo.name != "pypi" {
+		p.report.Infof("pypi", "%s to %s", verb, p.repo.uploadURL)
+	}
 
-	token := ""
-	if !cfg.DryRun {
-		var err error
-		token, err = mintToken()
+	ctx = p.report.ReportRetries(ctx, "pypi")
+	if p.creds.oidc {
+		// Minted here rather than in Validate, so that the token doesn't
+		// expire while other targets publish.
+		token, err := mintToken(ctx, p.repo)
 		if err != nil {
 			return err
 		}
+		p.creds.password = token
 	}
 
-	for _, a := range cfg.Artifacts {
-		w, err := buildWheel(cfg, a)
-		if err != nil {
-			return fmt.Errorf("failed to build wheel for %s/%s: %w", a.Platform.GOOS, a.Platform.GOARCH, err)
+	existing, err := p.existingFiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = publisher.Parallel(ctx, p.cfg.Concurrency, len(p.wheels), p.report, func(ctx context.Context, i int, report publisher.Reporter) error {
+		return p.upload(report.ReportRetries(ctx, "pypi"), p.wheels[i], existing, report)
+	})
+	publisher.SortLike(p.published, p.Packages())
+	publisher.SortLike(p.skipped, p.Packages())
+	if err != nil {
+		return err
+	}
+
+	p.report.Infof("pypi", "done")
+	return nil
+}
+
+func (p *Publisher) upload(ctx context.Context, w wheelFile, existing map[string]string, report publisher.Reporter) error {
+	done, err := p.alreadyUploaded(w.filename, w.sha256, existing)
+	if err != nil {
+		return err
+	}
+	if done {
+		p.mu.Lock()
+		p.skipped = append(p.skipped, w.filename)
+		p.mu.Unlock()
+		report.Skipped("pypi", w.filename)
+		return nil
+	}
+
+	report.Publishing("pypi", w.filename, p.cfg.DryRun)
+	if p.cfg.DryRun {
+		return nil
+	}
+	if err := uploadWheel(ctx, p.client, w, p.repo.uploadURL, p.creds); err != nil {
+		// A conflict may come from retrying an upload that went through
+		// before its response was lost.
+		if !errors.Is(err, errFileExists) || !p.uploadedEarlier(ctx, w) {
+			return fmt.Errorf("pypi: failed to upload %s: %w", w.filename, err)
 		}
-		fmt.Printf("pypi: %s %s...\n", verb, w.filename)
-		if cfg.DryRun {
-			continue
+	}
+	p.mu.Lock()
+	p.published = append(p.published, w.filename)
+	p.mu.Unlock()
+	report.Published("pypi", w.filename)
+	return p.journal.Record("pypi", w.filename, w.sha256)
+}
+
+func (p *Publisher) existingFiles(ctx context.Context) (map[string]string, error) {
+	if p.cfg.DryRun {
+		return nil, nil
+	}
+	if p.repo.jsonURL == "" {
+		p.report.Warnf("pypi", "can't look up files already uploaded to %s: only the journal is used to resume", p.repo.uploadURL)
+		return nil, nil
+	}
+	files, err := p.repo.releaseFiles(ctx, p.cfg.Name, p.version)
+	if err != nil {
+		return nil, fmt.Errorf("pypi: failed to look up %s %s: %w", p.cfg.Name, p.version, err)
+	}
+	return files, nil
+}
+
+func (p *Publisher) alreadyUploaded(filename, digest string, existing map[string]string) (bool, error) {
+	if p.journal.Digest("pypi", filename) == digest {
+		return true, nil
+	}
+	sum, ok := existing[filename]
+	if !ok {
+		return false, nil
+	}
+	if sum != digest {
+		return false, fmt.Errorf("pypi: %s is already uploaded with different contents: publish a new version instead", filename)
+	}
+	return true, nil
+}
+
+func (p *Publisher) uploadedEarlier(ctx context.Context, w wheelFile) bool {
+	if p.repo.jsonURL == "" {
+		return false
+	}
+	files, err := p.repo.releaseFiles(ctx, p.cfg.Name, p.version)
+	return err == nil && files[w.filename] == w.sha256
+}
+
+func (p *Publisher) Exists(ctx context.Context) ([]string, error) {
+	ctx = p.report.ReportRetries(ctx, "pypi")
+	repo := p.repo
+	if repo.uploadURL == "" {
+		var err error
+		if repo, err = resolveRepository(p.cfg.Repository, p.cfg.RepositoryURL); err != nil {
+			return nil, err
 		}
-		if err := uploadWheel(w, token); err != nil {
-			return fmt.Errorf("pypi: failed to upload %s: %w", w.filename, err)
+		repo.client = p.client
+	}
+
+	files, err := repo.releaseFiles(ctx, p.cfg.Name, p.version)
+	if err != nil {
+		return nil, fmt.Errorf("pypi: failed to look up %s %s: %w", p.cfg.Name, p.version, err)
+	}
+	var existing []string
+	for _, w := range p.wheels {
+		if _, ok := files[w.filename]; ok {
+			existing = append(existing, w.filename)
 		}
 	}
+	return existing, nil
+}
 
-	fmt.Println("pypi: done")
-	return nil
+func (p *Publisher) Pack(ctx context.Context, dir string) ([]publisher.PackedFile, error) {
+	if err := p.validateWheels(repository{}); err != nil {
+		return nil, err
+	}
+
+	var files []publisher.PackedFile
+	for _, w := range p.wheels {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		path := filepath.Join(dir, w.filename)
+		if err := copyFile(w.path, path, 0644); err != nil {
+			return nil, fmt.Errorf("pypi: failed to write %s: %w", path, err)
+		}
+		p.report.Infof("pypi", "wrote %s", path)
+		files = append(files, publisher.PackedFile{Package: w.filename, Path: path})
+	}
+	return files, nil
 }
 
-func uploadWheel(w wheelFile, token string) error {
-	hash := sha256.Sum256(w.data)
+func (p *Publisher) Packages() []string {
+	var names []string
+	for _, w := range p.wheels {
+		names = append(names, w.filename)
+	}
+	return names
+}
 
-	var body bytes.Buffer
-	mw := multipart.NewWriter(&body)
+func (p *Publisher) Published() []string {
+	return p.published
+}
 
+func (p *Publisher) Skipped() []string {
+	return p.skipped
+}
+
+func uploadWheel(ctx context.Context, client *httpclient.Client, w wheelFile, uploadURL string, creds credentials) error {
 	fields := [][2]string{
 		{":action", "file_upload"},
 		{"metadata_version", "2.1"},
@@ -61,7 +296,7 @@ func uploadWheel(w wheelFile, token string) error {
 		{"requires_python", ">=3.7"},
 		{"filetype", "bdist_wheel"},
 		{"pyversion", "py3"},
-		{"sha256_digest", hex.EncodeToString(hash[:])},
+		{"sha256_digest", w.sha256},
 		{"protocol_version", "1"},
 	}
 	if w.summary != "" {
@@ -76,37 +311,36 @@ func uploadWheel(w wheelFile, token string) error {
 			fields = append(fields, [2]string{"description_content_type", w.descContentType})
 		}
 	}
-	for _, f := range fields {
-		if err := mw.WriteField(f[0], f[1]); err != nil {
-			return err
-		}
-	}
 
-	fw, err := mw.CreateFormFile("content", w.filename)
+	form := uploadForm{fields: fields, wheel: w, boundary: multipart.NewWriter(io.Discard).Boundary()}
+	length, err := form.contentLength()
 	if err != nil {
 		return err
 	}
-	if _, err := io.Copy(fw, w.reader()); err != nil {
-		return err
-	}
-
-	if err := mw.Close(); err != nil {
+	body, err := form.body()
+	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, pypiUploadURL, &body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, body)
 	if err != nil {
+		_ = body.Close()
 		return err
 	}
-	req.Header.Set("Content-Type", mw.FormDataContentType())
-	req.SetBasicAuth("__token__", token)
+	req.ContentLength = length
+	req.GetBody = form.body
+	req.Header.Set("Content-Type", "multipart/form-data; boundary="+form.boundary)
+	req.SetBasicAuth(creds.username, creds.password)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if resp.StatusCode == http.StatusConflict || (resp.StatusCode == http.StatusBadRequest && fileExists(resp)) {
+		return errFileExists
+	}
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		return fmt.Errorf("%s", pypiError(resp.StatusCode))
 	}
@@ -114,10 +348,70 @@ func uploadWheel(w wheelFile, token string) error {
 	return nil
 }
 
+// uploadForm streams the multipart upload of a wheel from disk, so that the
+// request body is never held in memory.
+type uploadForm struct {
+	fields   [][2]string
+	wheel    wheelFile
+	boundary string
+}
+
+func (f uploadForm) write(w io.Writer, content io.Reader) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(f.boundary); err != nil {
+		return err
+	}
+	for _, field := range f.fields {
+		if err := mw.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+	fw, err := mw.CreateFormFile("content", f.wheel.filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(fw, content); err != nil {
+		return err
+	}
+	return mw.Close()
+}
+
+// contentLength writes the form without the wheel to find its size.
+func (f uploadForm) contentLength() (int64, error) {
+	var c countingWriter
+	if err := f.write(&c, strings.NewReader("")); err != nil {
+		return 0, err
+	}
+	return c.n + f.wheel.size, nil
+}
+
+func (f uploadForm) body() (io.ReadCloser, error) {
+	wheel, err := os.Open(f.wheel.path)
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		err := f.write(pw, wheel)
+		_ = wheel.Close()
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
+var errFileExists = errors.New("file already exists: this version has already been published")
+
+// fileExists reports whether a 400 is PyPI refusing a file it already has,
+// which it says in the status line.
+func fileExists(resp *http.Response) bool {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	return strings.Contains(strings.ToLower(resp.Status+" "+string(body)), "already exists")
+}
+
 func pypiError(status int) string {
 	switch status {
 	case http.StatusUnauthorized:
-		return "not authenticated: ensure your API token is valid"
+		return "not authenticated: ensure your API token or password is valid"
 	case http.StatusForbidden:
 		return "permission denied: the package name may be taken or your token lacks write access"
 	case http.StatusConflict:
@@ -127,6 +421,6 @@ func pypiError(status int) string {
 	case http.StatusBadRequest:
 		return "invalid package: check your metadata and version format"
 	default:
-		return fmt.Sprintf("unexpected status %d from PyPI", status)
+		return fmt.Sprintf("unexpected status %d from the repository", status)
 	}
 }
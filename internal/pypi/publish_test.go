@@ -3,13 +3,21 @@ package pypi
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"io"
 	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/jacobarthurs/shipbin/internal/config"
+	"github.com/jacobarthurs/shipbin/internal/httpclient"
+	"github.com/jacobarthurs/shipbin/internal/publisher"
 )
 
 func TestUploadWheel_Success(t *testing.T) {
@@ -58,20 +66,15 @@ func TestUploadWheel_Success(t *testing.T) {
 	}))
 	defer server.Close()
 
-	orig := pypiUploadURL
-	pypiUploadURL = server.URL
-	defer func() { pypiUploadURL = orig }()
-
-	wf := wheelFile{
+	wf := withData(t, wheelFile{
 		filename: "mytool-1.0.0-py3-none-linux_x86_64.whl",
 		pkgName:  "mytool",
 		version:  "1.0.0",
 		summary:  "A test tool",
 		license:  "MIT",
-		data:     data,
-	}
+	}, data)
 
-	if err := uploadWheel(wf, "secret-token"); err != nil {
+	if err := uploadWheel(t.Context(), nil, wf, server.URL, credentials{username: "__token__", password: "secret-token"}); err != nil {
 		t.Fatalf("uploadWheel: %v", err)
 	}
 
@@ -111,12 +114,8 @@ func TestUploadWheel_201Created(t *testing.T) {
 	}))
 	defer server.Close()
 
-	orig := pypiUploadURL
-	pypiUploadURL = server.URL
-	defer func() { pypiUploadURL = orig }()
-
-	wf := wheelFile{filename: "x.whl", pkgName: "x", version: "1.0.0", data: []byte("data")}
-	if err := uploadWheel(wf, "tok"); err != nil {
+	wf := withData(t, wheelFile{filename: "x.whl", pkgName: "x", version: "1.0.0"}, []byte("data"))
+	if err := uploadWheel(t.Context(), nil, wf, server.URL, credentials{username: "__token__", password: "tok"}); err != nil {
 		t.Fatalf("expected 201 to be treated as success, got: %v", err)
 	}
 }
@@ -142,12 +141,8 @@ func TestUploadWheel_OptionalFieldsOmitted(t *testing.T) {
 	}))
 	defer server.Close()
 
-	orig := pypiUploadURL
-	pypiUploadURL = server.URL
-	defer func() { pypiUploadURL = orig }()
-
-	wf := wheelFile{filename: "x.whl", pkgName: "x", version: "1.0.0", data: []byte("d")}
-	if err := uploadWheel(wf, "tok"); err != nil {
+	wf := withData(t, wheelFile{filename: "x.whl", pkgName: "x", version: "1.0.0"}, []byte("d"))
+	if err := uploadWheel(t.Context(), nil, wf, server.URL, credentials{username: "__token__", password: "tok"}); err != nil {
 		t.Fatalf("uploadWheel: %v", err)
 	}
 
@@ -169,12 +164,8 @@ func TestUploadWheel_NonOKStatus(t *testing.T) {
 	}))
 	defer server.Close()
 
-	orig := pypiUploadURL
-	pypiUploadURL = server.URL
-	defer func() { pypiUploadURL = orig }()
-
-	wf := wheelFile{filename: "x.whl", pkgName: "x", version: "1.0.0", data: []byte("d")}
-	err := uploadWheel(wf, "tok")
+	wf := withData(t, wheelFile{filename: "x.whl", pkgName: "x", version: "1.0.0"}, []byte("d"))
+	err := uploadWheel(t.Context(), nil, wf, server.URL, credentials{username: "__token__", password: "tok"})
 	if err == nil {
 		t.Fatal("expected error for non-200/201 status, got nil")
 	}
@@ -183,6 +174,56 @@ func TestUploadWheel_NonOKStatus(t *testing.T) {
 	}
 }
 
+func TestUploadWheel_Retries(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.ContentLength <= 0 || len(r.TransferEncoding) > 0 {
+			t.Errorf("attempt %d: Content-Length = %d, Transfer-Encoding = %v, want a known length", attempts, r.ContentLength, r.TransferEncoding)
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("attempt %d: ParseMultipartForm: %v", attempts, err)
+		}
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	var warnings []string
+	report := publisher.Reporter(func(e publisher.Event) { warnings = append(warnings, e.Message) })
+	client := httpclient.New(3, time.Minute, func(r httpclient.Retry) { report.Warnf("pypi", "%s", r) })
+	wf := withData(t, wheelFile{filename: "x.whl", pkgName: "x", version: "1.0.0"}, []byte("d"))
+	if err := uploadWheel(t.Context(), client, wf, server.URL, credentials{username: "__token__", password: "tok"}); err != nil {
+		t.Fatalf("uploadWheel: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "503") {
+		t.Errorf("warnings = %q, want one retry after the 503", warnings)
+	}
+}
+
+func TestUploadWheel_NoRetryOnConflict(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	wf := withData(t, wheelFile{filename: "x.whl", pkgName: "x", version: "1.0.0"}, []byte("d"))
+	if err := uploadWheel(t.Context(), httpclient.New(3, time.Minute, nil), wf, server.URL, credentials{}); err == nil {
+		t.Fatal("expected an error for a 400")
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
+
 func TestPypiError(t *testing.T) {
 	tests := []struct {
 		status  int
@@ -207,3 +248,362 @@ func TestPypiError(t *testing.T) {
 		})
 	}
 }
+
+func TestPublisherResume(t *testing.T) {
+	t.Setenv("PYPI_TOKEN", "pypi-secret")
+	p := NewPublisher(&Config{})
+	journal, err := publisher.OpenJournal(t.TempDir(), "mytool", "1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:      "mytool",
+		Version:   "1.0.0",
+		Artifacts: []config.Artifact{makeWheelArtifact(t, dir, "linux", "amd64"), makeWheelArtifact(t, dir, "darwin", "arm64"), makeWheelArtifact(t, dir, "windows", "amd64")},
+		Journal:   journal,
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	uploaded, recorded, fresh := p.wheels[0], p.wheels[1], p.wheels[2]
+	if err := journal.Record("pypi", recorded.filename, recorded.sha256); err != nil {
+		t.Fatal(err)
+	}
+
+	var uploads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = fmt.Fprintf(w, `{"urls":[{"filename":%q,"digests":{"sha256":%q}}]}`, uploaded.filename, uploaded.sha256)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("ParseMultipartForm: %v", err)
+		}
+		_, header, _ := r.FormFile("content")
+		uploads = append(uploads, header.Filename)
+	}))
+	defer server.Close()
+	defer func(upload, json string) { pypiUploadURL, pypiJSONURL = upload, json }(pypiUploadURL, pypiJSONURL)
+	pypiUploadURL, pypiJSONURL = server.URL+"/legacy/", server.URL+"/pypi/"
+
+	if err := p.Validate(t.Context()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if err := p.Publish(t.Context()); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if want := []string{fresh.filename}; !slices.Equal(uploads, want) || !slices.Equal(p.Published(), want) {
+		t.Errorf("uploaded %v, Published() = %v, want %v", uploads, p.Published(), want)
+	}
+	if want := []string{uploaded.filename, recorded.filename}; !slices.Equal(p.Skipped(), want) {
+		t.Errorf("Skipped() = %v, want %v", p.Skipped(), want)
+	}
+	if journal.Digest("pypi", fresh.filename) != fresh.sha256 {
+		t.Error("the uploaded wheel should be recorded in the journal")
+	}
+}
+
+func TestPublisherResume_DifferentContents(t *testing.T) {
+	t.Setenv("PYPI_TOKEN", "pypi-secret")
+	p := NewPublisher(&Config{})
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:      "mytool",
+		Version:   "1.0.0",
+		Artifacts: []config.Artifact{makeWheelArtifact(t, t.TempDir(), "linux", "amd64")},
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Error("a wheel with different contents was uploaded")
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"urls":[{"filename":%q,"digests":{"sha256":"0000"}}]}`, p.wheels[0].filename)
+	}))
+	defer server.Close()
+	defer func(upload, json string) { pypiUploadURL, pypiJSONURL = upload, json }(pypiUploadURL, pypiJSONURL)
+	pypiUploadURL, pypiJSONURL = server.URL+"/legacy/", server.URL+"/pypi/"
+
+	if err := p.Validate(t.Context()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if err := p.Publish(t.Context()); err == nil || !strings.Contains(err.Error(), "different contents") {
+		t.Fatalf("Publish() error = %v, want different contents", err)
+	}
+}
+
+func TestPublisher_Concurrent(t *testing.T) {
+	t.Setenv("PYPI_TOKEN", "pypi-secret")
+	var events []string
+	p := NewPublisher(&Config{})
+	dir := t.TempDir()
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:    "mytool",
+		Version: "1.0.0",
+		Artifacts: []config.Artifact{
+			makeWheelArtifact(t, dir, "linux", "amd64"), makeWheelArtifact(t, dir, "linux", "arm64"),
+			makeWheelArtifact(t, dir, "darwin", "arm64"), makeWheelArtifact(t, dir, "windows", "amd64"),
+		},
+		Concurrency: 3,
+		Report: func(e publisher.Event) {
+			if e.Kind == publisher.EventPublishing || e.Kind == publisher.EventPublished {
+				events = append(events, e.Package)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	var mu sync.Mutex
+	var uploads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("ParseMultipartForm: %v", err)
+			return
+		}
+		mu.Lock()
+		uploads = append(uploads, r.FormValue("sha256_digest"))
+		mu.Unlock()
+	}))
+	defer server.Close()
+	defer func(upload, json string) { pypiUploadURL, pypiJSONURL = upload, json }(pypiUploadURL, pypiJSONURL)
+	pypiUploadURL, pypiJSONURL = server.URL+"/legacy/", server.URL+"/pypi/"
+
+	if err := p.Validate(t.Context()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if err := p.Publish(t.Context()); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	if len(uploads) != 4 || len(p.Published()) != 4 {
+		t.Fatalf("uploaded %d wheels, Published() = %v, want 4", len(uploads), p.Published())
+	}
+	var want []string
+	for _, w := range p.wheels {
+		want = append(want, w.filename, w.filename)
+	}
+	if !slices.Equal(events, want) {
+		t.Errorf("events = %v, want each wheel's in order %v", events, want)
+	}
+}
+
+func TestPublisher_ConcurrentRetries(t *testing.T) {
+	t.Setenv("PYPI_TOKEN", "pypi-secret")
+	var events []string
+	p := NewPublisher(&Config{})
+	dir := t.TempDir()
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:    "mytool",
+		Version: "1.0.0",
+		Artifacts: []config.Artifact{
+			makeWheelArtifact(t, dir, "linux", "amd64"), makeWheelArtifact(t, dir, "linux", "arm64"),
+			makeWheelArtifact(t, dir, "darwin", "arm64"), makeWheelArtifact(t, dir, "windows", "amd64"),
+		},
+		Concurrency: 4,
+		Retries:     1,
+		Report: func(e publisher.Event) {
+			switch e.Kind {
+			case publisher.EventPublishing, publisher.EventPublished:
+				events = append(events, e.Package)
+			case publisher.EventWarning:
+				events = append(events, "retry")
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("ParseMultipartForm: %v", err)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		digest := r.FormValue("sha256_digest")
+		if attempts[digest]++; attempts[digest] == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+	defer func(upload, json string) { pypiUploadURL, pypiJSONURL = upload, json }(pypiUploadURL, pypiJSONURL)
+	pypiUploadURL, pypiJSONURL = server.URL+"/legacy/", server.URL+"/pypi/"
+
+	if err := p.Validate(t.Context()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if err := p.Publish(t.Context()); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	var want []string
+	for _, w := range p.wheels {
+		want = append(want, w.filename, "retry", w.filename)
+	}
+	if !slices.Equal(events, want) {
+		t.Errorf("events = %v, want each wheel's retry between its own events %v", events, want)
+	}
+}
+
+func TestPublisher_ConflictAfterRetry(t *testing.T) {
+	t.Setenv("PYPI_TOKEN", "pypi-secret")
+	p := NewPublisher(&Config{})
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:      "mytool",
+		Version:   "1.0.0",
+		Artifacts: []config.Artifact{makeWheelArtifact(t, t.TempDir(), "linux", "amd64")},
+		Retries:   1,
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+	w := p.wheels[0]
+
+	var uploads int
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			if uploads == 0 {
+				http.NotFound(rw, r)
+				return
+			}
+			_, _ = fmt.Fprintf(rw, `{"urls":[{"filename":%q,"digests":{"sha256":%q}}]}`, w.filename, w.sha256)
+			return
+		}
+		_, _ = io.Copy(io.Discard, r.Body)
+		if uploads++; uploads == 1 {
+			rw.Header().Set("Retry-After", "0")
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		http.Error(rw, "File already exists ('"+w.filename+"', with blake2_256 hash 'abc').", http.StatusBadRequest)
+	}))
+	defer server.Close()
+	defer func(upload, json string) { pypiUploadURL, pypiJSONURL = upload, json }(pypiUploadURL, pypiJSONURL)
+	pypiUploadURL, pypiJSONURL = server.URL+"/legacy/", server.URL+"/pypi/"
+
+	if err := p.Validate(t.Context()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if err := p.Publish(t.Context()); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if uploads != 2 {
+		t.Errorf("uploads = %d, want 2", uploads)
+	}
+	if want := []string{w.filename}; !slices.Equal(p.Published(), want) {
+		t.Errorf("Published() = %v, want %v", p.Published(), want)
+	}
+}
+
+func TestPublisher_ConflictWithDifferentContents(t *testing.T) {
+	t.Setenv("PYPI_TOKEN", "pypi-secret")
+	p := NewPublisher(&Config{})
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:      "mytool",
+		Version:   "1.0.0",
+		Artifacts: []config.Artifact{makeWheelArtifact(t, t.TempDir(), "linux", "amd64")},
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	var uploads int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			if uploads == 0 {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"urls":[{"filename":%q,"digests":{"sha256":"0000"}}]}`, p.wheels[0].filename)
+			return
+		}
+		_, _ = io.Copy(io.Discard, r.Body)
+		uploads++
+		http.Error(w, "File already exists.", http.StatusBadRequest)
+	}))
+	defer server.Close()
+	defer func(upload, json string) { pypiUploadURL, pypiJSONURL = upload, json }(pypiUploadURL, pypiJSONURL)
+	pypiUploadURL, pypiJSONURL = server.URL+"/legacy/", server.URL+"/pypi/"
+
+	if err := p.Validate(t.Context()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if err := p.Publish(t.Context()); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("Publish() error = %v, want already exists", err)
+	}
+}
+
+func TestPublisher_MintsTokenWhenPublishing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PYPI_TOKEN", "")
+	t.Setenv("PYPI_PASSWORD", "")
+	p := NewPublisher(&Config{})
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:      "mytool",
+		Version:   "1.0.0",
+		Artifacts: []config.Artifact{makeWheelArtifact(t, t.TempDir(), "linux", "amd64")},
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	var minted int
+	var password string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oidc":
+			_, _ = w.Write([]byte(`{"value":"oidc-jwt"}`))
+		case "/mint":
+			minted++
+			_, _ = w.Write([]byte(`{"token":"pypi-minted"}`))
+		case "/legacy/":
+			_, _ = io.Copy(io.Discard, r.Body)
+			_, password, _ = r.BasicAuth()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", server.URL+"/oidc")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "request-token")
+	defer func(upload, json, mint string) { pypiUploadURL, pypiJSONURL, pypiMintTokenURL = upload, json, mint }(pypiUploadURL, pypiJSONURL, pypiMintTokenURL)
+	pypiUploadURL, pypiJSONURL, pypiMintTokenURL = server.URL+"/legacy/", server.URL+"/pypi/", server.URL+"/mint"
+
+	if err := p.Validate(t.Context()); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if minted != 0 {
+		t.Fatal("Validate minted an upload token, which could expire before PyPI publishes")
+	}
+	if err := p.Publish(t.Context()); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if minted != 1 || password != "pypi-minted" {
+		t.Errorf("minted %d tokens and uploaded with %q, want one minted token", minted, password)
+	}
+}
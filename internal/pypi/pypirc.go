@@ -0,0 +1,85 @@
+package pypi
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type pypircSection struct {
+	repository string
+	username   string
+	password   string
+}
+
+type pypirc struct {
+	path     string
+	sections map[string]pypircSection
+}
+
+func loadPypirc() (pypirc, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return pypirc{}, nil
+	}
+	return readPypirc(filepath.Join(home, ".pypirc"))
+}
+
+func readPypirc(path string) (pypirc, error) {
+	rc := pypirc{path: path, sections: make(map[string]pypircSection)}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return rc, nil
+		}
+		return rc, err
+	}
+	defer func() { _ = f.Close() }()
+
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i < 0 || section == "" {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+
+		s := rc.sections[section]
+		switch key {
+		case "repository":
+			s.repository = value
+		case "username":
+			s.username = value
+		case "password":
+			s.password = value
+		}
+		rc.sections[section] = s
+	}
+	return rc, scanner.Err()
+}
+
+func (rc pypirc) forURL(uploadURL string) (pypircSection, bool) {
+	for _, s := range rc.sections {
+		if s.repository != "" && sameURL(s.repository, uploadURL) {
+			return s, true
+		}
+	}
+	return pypircSection{}, false
+}
+
+func sameURL(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
@@ -0,0 +1,178 @@
+package pypi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/jacobarthurs/shipbin/internal/httpclient"
+	"github.com/jacobarthurs/shipbin/internal/publisher"
+)
+
+var (
+	pypiJSONURL          = "https://pypi.org/pypi/"
+	testPyPIUploadURL    = "https://test.pypi.org/legacy/"
+	testPyPIMintTokenURL = "https://test.pypi.org/_/oidc/mint-token"
+	testPyPIJSONURL      = "https://test.pypi.org/pypi/"
+)
+
+type repository struct {
+	name         string
+	uploadURL    string
+	mintTokenURL string
+	audience     string
+	jsonURL      string
+	maxFileSize  int64
+	username     string
+	password     string
+	rcPath       string
+	client       *httpclient.Client
+}
+
+type credentials struct {
+	username string
+	password string
+	// oidc means the password is an upload token to mint from a trusted
+	// publisher right before uploading, as it expires after 15 minutes.
+	oidc bool
+}
+
+func knownRepository(name string) (repository, bool) {
+	switch name {
+	case "pypi":
+		return repository{name: "pypi", uploadURL: pypiUploadURL, mintTokenURL: pypiMintTokenURL, audience: "pypi", jsonURL: pypiJSONURL, maxFileSize: pypiMaxFileSize}, true
+	case "testpypi":
+		return repository{name: "testpypi", uploadURL: testPyPIUploadURL, mintTokenURL: testPyPIMintTokenURL, audience: "testpypi", jsonURL: testPyPIJSONURL, maxFileSize: pypiMaxFileSize}, true
+	}
+	return repository{}, false
+}
+
+// public reports whether r is PyPI or TestPyPI, which are stricter than
+// other repositories about versions and platform tags.
+func (r repository) public() bool {
+	return r.name == "pypi" || r.name == "testpypi"
+}
+
+func resolveRepository(name, uploadURL string) (repository, error) {
+	rc, err := loadPypirc()
+	if err != nil {
+		return repository{}, fmt.Errorf("pypi: failed to read %s: %w", rc.path, err)
+	}
+
+	if uploadURL != "" {
+		if _, err := url.ParseRequestURI(uploadURL); err != nil {
+			return repository{}, fmt.Errorf("pypi: invalid repository URL %q: %w", uploadURL, err)
+		}
+		repo := repository{name: uploadURL, uploadURL: uploadURL}
+		for _, known := range []string{"pypi", "testpypi"} {
+			if k, _ := knownRepository(known); sameURL(k.uploadURL, uploadURL) {
+				repo = k
+			}
+		}
+		if s, ok := rc.forURL(uploadURL); ok {
+			repo.username, repo.password, repo.rcPath = s.username, s.password, rc.path
+		}
+		return repo, nil
+	}
+
+	if name == "" {
+		name = "pypi"
+	}
+	s, inRC := rc.sections[name]
+	repo, known := knownRepository(name)
+	if !known {
+		if !inRC || s.repository == "" {
+			return repository{}, fmt.Errorf("pypi: unknown repository %q: use pypi, testpypi, --repository-url, or a [%s] section with a repository URL in ~/.pypirc", name, name)
+		}
+		repo = repository{name: name, uploadURL: s.repository}
+	}
+	if inRC {
+		repo.username, repo.password, repo.rcPath = s.username, s.password, rc.path
+	}
+	return repo, nil
+}
+
+func (r repository) credentials(report publisher.Reporter) (credentials, error) {
+	if token := os.Getenv("PYPI_TOKEN"); token != "" {
+		report.Infof("pypi", "authenticating with PYPI_TOKEN")
+		return credentials{username: "__token__", password: token}, nil
+	}
+
+	if password := os.Getenv("PYPI_PASSWORD"); password != "" {
+		username := os.Getenv("PYPI_USERNAME")
+		if username == "" {
+			username = "__token__"
+		}
+		report.Infof("pypi", "authenticating to %s as %s with PYPI_PASSWORD", r.name, username)
+		return credentials{username: username, password: password}, nil
+	}
+
+	if r.password != "" {
+		username := r.username
+		if username == "" {
+			username = "__token__"
+		}
+		report.Infof("pypi", "authenticating to %s as %s with credentials from %s", r.name, username, r.rcPath)
+		return credentials{username: username, password: r.password}, nil
+	}
+
+	if r.mintTokenURL == "" {
+		return credentials{}, fmt.Errorf(
+			"pypi: no credentials found for %s\n"+
+				"set PYPI_TOKEN, or PYPI_USERNAME and PYPI_PASSWORD, or\n"+
+				"add a username and password for it to ~/.pypirc",
+			r.uploadURL,
+		)
+	}
+
+	if _, _, err := oidcRequest(r); err != nil {
+		return credentials{}, err
+	}
+	report.Infof("pypi", "authenticating with OIDC trusted publisher")
+	return credentials{username: "__token__", oidc: true}, nil
+}
+
+func (r repository) releaseFiles(ctx context.Context, name, version string) (map[string]string, error) {
+	if r.jsonURL == "" {
+		return nil, fmt.Errorf("looking up existing files is only supported on pypi and testpypi, not %s", r.uploadURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%s/%s/json", strings.TrimSuffix(r.jsonURL, "/"), url.PathEscape(name), url.PathEscape(version)), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, r.name)
+	}
+
+	var release struct {
+		URLs []struct {
+			Filename string `json:"filename"`
+			Digests  struct {
+				SHA256 string `json:"sha256"`
+			} `json:"digests"`
+		} `json:"urls"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, err
+	}
+	files := make(map[string]string, len(release.URLs))
+	for _, u := range release.URLs {
+		files[u.Filename] = u.Digests.SHA256
+	}
+	return files, nil
+}
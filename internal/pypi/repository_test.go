@@ -0,0 +1,179 @@
+package pypi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePypirc(t *testing.T, content string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := filepath.Join(home, ".pypirc")
+	if content != "" {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+const testPypirc = `[distutils]
+index-servers =
+    pypi
+    internal
+
+[pypi]
+username = __token__
+password = pypi-token
+
+# Artifactory
+[internal]
+repository: https://artifactory.example.com/api/pypi/pypi-local
+username: deploy
+password: hunter2
+`
+
+func TestReadPypirc(t *testing.T) {
+	path := writePypirc(t, testPypirc)
+	rc, err := readPypirc(path)
+	if err != nil {
+		t.Fatalf("readPypirc: %v", err)
+	}
+
+	want := pypircSection{repository: "https://artifactory.example.com/api/pypi/pypi-local", username: "deploy", password: "hunter2"}
+	if got := rc.sections["internal"]; got != want {
+		t.Errorf("internal = %+v, want %+v", got, want)
+	}
+	if got := rc.sections["pypi"]; got.username != "__token__" || got.password != "pypi-token" {
+		t.Errorf("pypi = %+v", got)
+	}
+	if s, ok := rc.forURL("https://artifactory.example.com/api/pypi/pypi-local/"); !ok || s.username != "deploy" {
+		t.Errorf("forURL() = %+v, %v, want the internal section", s, ok)
+	}
+}
+
+func TestResolveRepository(t *testing.T) {
+	writePypirc(t, testPypirc)
+
+	tests := []struct {
+		name       string
+		repository string
+		url        string
+		wantUpload string
+		wantMint   string
+		wantUser   string
+	}{
+		{"default", "", "", pypiUploadURL, pypiMintTokenURL, "__token__"},
+		{"testpypi", "testpypi", "", testPyPIUploadURL, testPyPIMintTokenURL, ""},
+		{"pypirc section", "internal", "", "https://artifactory.example.com/api/pypi/pypi-local", "", "deploy"},
+		{"known url", "", "https://test.pypi.org/legacy", testPyPIUploadURL, testPyPIMintTokenURL, ""},
+		{"url in pypirc", "", "https://artifactory.example.com/api/pypi/pypi-local/", "https://artifactory.example.com/api/pypi/pypi-local", "", "deploy"},
+		{"url wins", "testpypi", "https://devpi.example.com/root/dev/", "https://devpi.example.com/root/dev/", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := resolveRepository(tt.repository, tt.url)
+			if err != nil {
+				t.Fatalf("resolveRepository: %v", err)
+			}
+			if repo.mintTokenURL != tt.wantMint || repo.username != tt.wantUser {
+				t.Errorf("repository = %+v, want mint %q and user %q", repo, tt.wantMint, tt.wantUser)
+			}
+			if !sameURL(repo.uploadURL, tt.wantUpload) {
+				t.Errorf("uploadURL = %q, want %q", repo.uploadURL, tt.wantUpload)
+			}
+		})
+	}
+}
+
+func TestResolveRepository_Unknown(t *testing.T) {
+	writePypirc(t, "")
+	_, err := resolveRepository("internal", "")
+	if err == nil || !strings.Contains(err.Error(), "unknown repository") {
+		t.Errorf("resolveRepository() error = %v, want unknown repository", err)
+	}
+}
+
+func TestRepositoryCredentials(t *testing.T) {
+	writePypirc(t, testPypirc)
+	t.Setenv("PYPI_TOKEN", "")
+	t.Setenv("PYPI_USERNAME", "")
+	t.Setenv("PYPI_PASSWORD", "")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "")
+
+	repo, err := resolveRepository("internal", "")
+	if err != nil {
+		t.Fatalf("resolveRepository: %v", err)
+	}
+	if got, err := repo.credentials(nil); err != nil || got != (credentials{username: "deploy", password: "hunter2"}) {
+		t.Errorf("credentials() from .pypirc = %+v, %v", got, err)
+	}
+
+	t.Setenv("PYPI_USERNAME", "ci")
+	t.Setenv("PYPI_PASSWORD", "from-env")
+	if got, err := repo.credentials(nil); err != nil || got != (credentials{username: "ci", password: "from-env"}) {
+		t.Errorf("credentials() from PYPI_PASSWORD = %+v, %v", got, err)
+	}
+
+	t.Setenv("PYPI_TOKEN", "pypi-abc")
+	if got, err := repo.credentials(nil); err != nil || got != (credentials{username: "__token__", password: "pypi-abc"}) {
+		t.Errorf("credentials() from PYPI_TOKEN = %+v, %v", got, err)
+	}
+}
+
+func TestRepositoryCredentials_Missing(t *testing.T) {
+	writePypirc(t, "")
+	t.Setenv("PYPI_TOKEN", "")
+	t.Setenv("PYPI_PASSWORD", "")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "")
+
+	repo, err := resolveRepository("", "https://nexus.example.com/repository/pypi-internal/")
+	if err != nil {
+		t.Fatalf("resolveRepository: %v", err)
+	}
+	_, err = repo.credentials(nil)
+	if err == nil || !strings.Contains(err.Error(), "PYPI_USERNAME and PYPI_PASSWORD") {
+		t.Errorf("credentials() error = %v, want a hint about basic auth", err)
+	}
+
+	repo, _ = resolveRepository("testpypi", "")
+	_, err = repo.credentials(nil)
+	if err == nil || !strings.Contains(err.Error(), "https://test.pypi.org/manage/account/publishing/") {
+		t.Errorf("credentials() error = %v, want the TestPyPI trusted publisher page", err)
+	}
+}
+
+func TestReleaseFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/pypi/mytool/1.0.0/json" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"urls":[{"filename":"mytool-1.0.0-py3-none-win_amd64.whl","digests":{"sha256":"abc"}}]}`))
+	}))
+	defer server.Close()
+
+	repo := repository{name: "pypi", jsonURL: server.URL + "/pypi/"}
+	files, err := repo.releaseFiles(t.Context(), "mytool", "1.0.0")
+	if err != nil {
+		t.Fatalf("releaseFiles: %v", err)
+	}
+	if files["mytool-1.0.0-py3-none-win_amd64.whl"] != "abc" || len(files) != 1 {
+		t.Errorf("releaseFiles() = %v", files)
+	}
+
+	files, err = repo.releaseFiles(t.Context(), "mytool", "2.0.0")
+	if err != nil || len(files) != 0 {
+		t.Errorf("releaseFiles() for an unpublished version = %v, %v, want none", files, err)
+	}
+
+	_, err = repository{name: "internal", uploadURL: "https://nexus.example.com/"}.releaseFiles(t.Context(), "mytool", "1.0.0")
+	if err == nil {
+		t.Error("releaseFiles() on a repository without a JSON API should fail")
+	}
+}
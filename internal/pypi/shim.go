@@ -2,12 +2,43 @@ package pypi
 
 import (
 	_ "embed"
+	"fmt"
+	"regexp"
 	"strings"
 )
 
 //go:embed shim.py
 var shimTemplate string
 
-func renderShim(name string) ([]byte, error) {
-	return []byte(strings.ReplaceAll(shimTemplate, "__BIN_NAME__", name)), nil
+var identRe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+func renderShim(pkgName string, executables []string) ([]byte, error) {
+	var entries strings.Builder
+	funcs := entryPointFuncs(executables)
+	for i, exe := range executables {
+		fmt.Fprintf(&entries, "\n\ndef %s():\n    _run(%q)\n", funcs[i], exe)
+	}
+	s := strings.ReplaceAll(shimTemplate, "__PKG_NAME__", pkgName)
+	return []byte(strings.ReplaceAll(s, "__ENTRY_POINTS__\n", entries.String())), nil
+}
+
+// entryPointFuncs names the shim function of each executable. Names that
+// are the same once sanitized, like a-b and a_b, get a numeric suffix, as
+// the second definition would otherwise replace the first.
+func entryPointFuncs(executables []string) []string {
+	funcs := make([]string, len(executables))
+	taken := make(map[string]bool)
+	for i, exe := range executables {
+		name := "main"
+		if i > 0 {
+			name = "main_" + identRe.ReplaceAllString(exe, "_")
+		}
+		unique := name
+		for n := 2; taken[unique]; n++ {
+			unique = fmt.Sprintf("%s_%d", name, n)
+		}
+		taken[unique] = true
+		funcs[i] = unique
+	}
+	return funcs
 }
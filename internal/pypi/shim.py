@@ -4,20 +4,20 @@ import sys
 import platform
 
 
-def main():
+def _run(name):
     system = platform.system().lower()
 
     if system == "windows":
-        binary_name = "__BIN_NAME__.exe"
+        binary_name = name + ".exe"
     else:
-        binary_name = "__BIN_NAME__"
+        binary_name = name
 
     binary_path = os.path.join(os.path.dirname(__file__), "bin", binary_name)
 
     if not os.path.isfile(binary_path):
         print(
-            f"__BIN_NAME__: binary not found at {binary_path}\n"
-            f"try reinstalling: pip install __BIN_NAME__",
+            f"{name}: binary not found at {binary_path}\n"
+            f"try reinstalling: pip install __PKG_NAME__",
             file=sys.stderr,
         )
         sys.exit(1)
@@ -27,3 +27,4 @@ def main():
         sys.exit(proc.returncode)
     else:
         os.execv(binary_path, [binary_path] + sys.argv[1:])
+__ENTRY_POINTS__
@@ -1,19 +1,20 @@
 package pypi
 
 import (
+	"slices"
 	"strings"
 	"testing"
 )
 
 func TestRenderShim_ReplacesPlaceholder(t *testing.T) {
-	shim, err := renderShim("mytool")
+	shim, err := renderShim("mytool", []string{"mytool"})
 	if err != nil {
 		t.Fatalf("renderShim: %v", err)
 	}
 	s := string(shim)
 
-	if strings.Contains(s, "__BIN_NAME__") {
-		t.Error("renderShim did not replace __BIN_NAME__")
+	if strings.Contains(s, "__PKG_NAME__") || strings.Contains(s, "__ENTRY_POINTS__") {
+		t.Error("renderShim did not replace placeholders")
 	}
 	if !strings.Contains(s, "mytool") {
 		t.Error("renderShim does not contain the binary name")
@@ -21,7 +22,7 @@ func TestRenderShim_ReplacesPlaceholder(t *testing.T) {
 }
 
 func TestRenderShim_ContainsRuntimeCalls(t *testing.T) {
-	shim, err := renderShim("mytool")
+	shim, err := renderShim("mytool", []string{"mytool"})
 	if err != nil {
 		t.Fatalf("renderShim: %v", err)
 	}
@@ -36,11 +37,11 @@ func TestRenderShim_ContainsRuntimeCalls(t *testing.T) {
 }
 
 func TestRenderShim_DifferentNames(t *testing.T) {
-	s1, err := renderShim("foo")
+	s1, err := renderShim("foo", []string{"foo"})
 	if err != nil {
 		t.Fatal(err)
 	}
-	s2, err := renderShim("bar")
+	s2, err := renderShim("bar", []string{"bar"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -55,3 +56,39 @@ func TestRenderShim_DifferentNames(t *testing.T) {
 		t.Error("s2 should reference 'bar' not 'foo'")
 	}
 }
+
+func TestRenderShim_MultipleExecutables(t *testing.T) {
+	shim, err := renderShim("mytool", []string{"mytool", "mytool-lsp"})
+	if err != nil {
+		t.Fatalf("renderShim: %v", err)
+	}
+	s := string(shim)
+
+	for _, want := range []string{
+		"def main():\n    _run(\"mytool\")",
+		"def main_mytool_lsp():\n    _run(\"mytool-lsp\")",
+		"pip install mytool",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("shim missing %q", want)
+		}
+	}
+}
+
+func TestEntryPointFuncs_Collisions(t *testing.T) {
+	got := entryPointFuncs([]string{"mytool", "a-b", "a_b", "a.b", "a_b_2"})
+	want := []string{"main", "main_a_b", "main_a_b_2", "main_a_b_3", "main_a_b_2_2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("entryPointFuncs() = %v, want %v", got, want)
+	}
+
+	shim, err := renderShim("mytool", []string{"mytool", "a-b", "a_b"})
+	if err != nil {
+		t.Fatalf("renderShim: %v", err)
+	}
+	for _, want := range []string{"def main_a_b():\n    _run(\"a-b\")", "def main_a_b_2():\n    _run(\"a_b\")"} {
+		if !strings.Contains(string(shim), want) {
+			t.Errorf("shim missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package pypi
+
+import (
+	"archive/zip"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// PyPI's default upload limit per file; projects can ask for more.
+const pypiMaxFileSize = 100 << 20
+
+var (
+	projectNameRe = regexp.MustCompile(`(?i)^([a-z0-9]|[a-z0-9][a-z0-9._-]*[a-z0-9])$`)
+	platformTagRe = regexp.MustCompile(`^(manylinux_\d+_\d+|manylinux1|manylinux2010|manylinux2014|musllinux_\d+_\d+|macosx_\d+_\d+|linux)_[a-z0-9_]+$|^(win32|win_amd64|win_arm64)$`)
+)
+
+func (p *Publisher) validateWheels(repo repository) error {
+	var errs []error
+	if strings.Contains(p.version, "+") && repo.public() {
+		errs = append(errs, fmt.Errorf("pypi: version %s is a local version, which %s doesn't accept", p.version, repo.name))
+	}
+	if !projectNameRe.MatchString(p.cfg.Name) {
+		errs = append(errs, fmt.Errorf("pypi: invalid project name %q: use letters, digits, '.', '_' and '-', starting and ending with a letter or digit", p.cfg.Name))
+	}
+	if strings.ContainsAny(p.cfg.Summary, "\r\n") {
+		errs = append(errs, fmt.Errorf("pypi: the summary must be a single line"))
+	}
+	if n := len([]rune(p.cfg.Summary)); n > 512 {
+		errs = append(errs, fmt.Errorf("pypi: the summary is %d characters, over PyPI's limit of 512", n))
+	}
+
+	var seen []string
+	for _, w := range p.wheels {
+		if slices.Contains(seen, w.filename) {
+			errs = append(errs, fmt.Errorf("pypi: %s: built from more than one artifact", w.filename))
+			continue
+		}
+		seen = append(seen, w.filename)
+		for _, err := range checkWheel(w, repo.maxFileSize) {
+			errs = append(errs, fmt.Errorf("pypi: %s: %w", w.filename, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func checkWheel(w wheelFile, maxSize int64) []error {
+	var errs []error
+	if maxSize > 0 && w.size > maxSize {
+		errs = append(errs, fmt.Errorf("%d MB is over the upload limit of %d MB: if the limit was raised for this project, pass --max-file-size", w.size>>20, maxSize>>20))
+	}
+
+	parts := strings.Split(strings.TrimSuffix(w.filename, ".whl"), "-")
+	if !strings.HasSuffix(w.filename, ".whl") || len(parts) != 5 {
+		return append(errs, fmt.Errorf("filename must be name-version-python-abi-platform.whl"))
+	}
+	if parts[0] != w.pkgName || parts[1] != w.version {
+		errs = append(errs, fmt.Errorf("filename doesn't match %s %s", w.pkgName, w.version))
+	}
+	var tags []string
+	for _, plat := range strings.Split(parts[4], ".") {
+		if !platformTagRe.MatchString(plat) {
+			errs = append(errs, fmt.Errorf("invalid platform tag %q", plat))
+		}
+		tags = append(tags, parts[2]+"-"+parts[3]+"-"+plat)
+	}
+
+	zr, err := zip.OpenReader(w.path)
+	if err != nil {
+		return append(errs, fmt.Errorf("not a valid zip archive: %w", err))
+	}
+	defer func() { _ = zr.Close() }()
+	distInfo := w.pkgName + "-" + w.version + ".dist-info/"
+	metadata, err := readHeaders(&zr.Reader, distInfo+"METADATA")
+	if err != nil {
+		return append(errs, err)
+	}
+	if name, version := metadata.get("Name"), metadata.get("Version"); name != w.pkgName || version != w.version {
+		errs = append(errs, fmt.Errorf("METADATA is for %q %q", name, version))
+	}
+	wheel, err := readHeaders(&zr.Reader, distInfo+"WHEEL")
+	if err != nil {
+		return append(errs, err)
+	}
+	if got := wheel["Tag"]; !slices.Equal(got, tags) {
+		errs = append(errs, fmt.Errorf("WHEEL tags %v don't match the filename", got))
+	}
+	return errs
+}
+
+type headers map[string][]string
+
+func (h headers) get(key string) string {
+	if len(h[key]) == 0 {
+		return ""
+	}
+	return h[key][0]
+}
+
+func readHeaders(zr *zip.Reader, name string) (headers, error) {
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("missing %s", name)
+	}
+	defer func() { _ = f.Close() }()
+
+	h := make(headers)
+	scanner := bufio.NewScanner(io.LimitReader(f, 1<<20))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return nil, fmt.Errorf("malformed %s header %q", name, line)
+		}
+		h[key] = append(h[key], value)
+	}
+	return h, scanner.Err()
+}
@@ -0,0 +1,219 @@
+package pypi
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jacobarthurs/shipbin/internal/config"
+	"github.com/jacobarthurs/shipbin/internal/publisher"
+)
+
+func TestPlatformTagRe(t *testing.T) {
+	valid := []string{
+		"manylinux_2_17_x86_64", "manylinux2014_aarch64", "musllinux_1_2_armv7l", "macosx_11_0_universal2",
+		"linux_armv6l", "linux_riscv64", "win_amd64", "win_arm64", "win32",
+	}
+	for _, tag := range valid {
+		if !platformTagRe.MatchString(tag) {
+			t.Errorf("%q should be a valid platform tag", tag)
+		}
+	}
+	for _, tag := range []string{"", "any", "manylinux_x86_64", "macosx_arm64", "Linux_x86_64", "win-amd64"} {
+		if platformTagRe.MatchString(tag) {
+			t.Errorf("%q should be an invalid platform tag", tag)
+		}
+	}
+}
+
+func TestCheckWheel(t *testing.T) {
+	cfg := &Config{Name: "mytool", Version: "1.0.0"}
+	w, err := buildWheel(cfg, makeWheelArtifact(t, t.TempDir(), "windows", "amd64"), t.TempDir())
+	if err != nil {
+		t.Fatalf("buildWheel: %v", err)
+	}
+	if errs := checkWheel(w, pypiMaxFileSize); len(errs) != 0 {
+		t.Fatalf("checkWheel() = %v for a good wheel", errs)
+	}
+
+	renamed := w
+	renamed.filename = "mytool-1.0.0-py3-none-win_arm64.whl"
+	if errs := checkWheel(renamed, 0); len(errs) != 1 || !strings.Contains(errs[0].Error(), "WHEEL tags") {
+		t.Errorf("checkWheel() = %v, want mismatched WHEEL tags", errs)
+	}
+
+	tooBig := w
+	if errs := checkWheel(tooBig, w.size-1); len(errs) != 1 || !strings.Contains(errs[0].Error(), "upload limit") {
+		t.Errorf("checkWheel() = %v, want the size limit", errs)
+	}
+
+	broken := withData(t, w, []byte("not a zip"))
+	if errs := checkWheel(broken, 0); len(errs) != 1 || !strings.Contains(errs[0].Error(), "zip") {
+		t.Errorf("checkWheel() = %v, want an invalid zip", errs)
+	}
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	dir := t.TempDir()
+	p := NewPublisher(&Config{})
+	cleanup, err := p.Build(t.Context(), publisher.Release{
+		Name:    "my tool",
+		Version: "1.0.0",
+		Summary: "first line\nsecond line",
+		Artifacts: []config.Artifact{
+			makeWheelArtifact(t, dir, "linux", "amd64"),
+			makeWheelArtifact(t, dir, "linux", "amd64"),
+			makeWheelArtifact(t, dir, "windows", "amd64"),
+		},
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+
+	err = p.Validate(t.Context())
+	if err == nil {
+		t.Fatal("Validate() = nil, want errors")
+	}
+	for _, want := range []string{"invalid project name", "single line", "built from more than one artifact"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error = %v, want it to mention %q", err, want)
+		}
+	}
+}
+
+func TestBuild_ReportsEveryArtifact(t *testing.T) {
+	dir := t.TempDir()
+	linux := makeWheelArtifact(t, dir, "linux", "amd64")
+	windows := makeWheelArtifact(t, dir, "windows", "amd64")
+	for _, a := range []config.Artifact{linux, windows} {
+		if err := os.Remove(a.Executables[0].Path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := NewPublisher(&Config{})
+	_, err := p.Build(t.Context(), publisher.Release{Name: "mytool", Version: "1.0.0", Artifacts: []config.Artifact{linux, windows}})
+	if err == nil {
+		t.Fatal("Build() = nil, want errors")
+	}
+	for _, want := range []string{"linux/amd64", "windows/amd64"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Build() error = %v, want it to mention %s", err, want)
+		}
+	}
+}
+
+func TestValidate_MaxFileSize(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, tt := range []struct {
+		maxFileSize int64
+		wantErr     bool
+	}{
+		{0, false},
+		{1, true},
+	} {
+		p := NewPublisher(&Config{Repository: "testpypi", MaxFileSize: tt.maxFileSize})
+		cleanup, err := p.Build(t.Context(), publisher.Release{
+			Name:      "mytool",
+			Version:   "1.0.0",
+			Artifacts: []config.Artifact{makeWheelArtifact(t, t.TempDir(), "linux", "amd64")},
+			DryRun:    true,
+		})
+		if err != nil {
+			t.Fatalf("Build: %v", err)
+		}
+		err = p.Validate(t.Context())
+		cleanup()
+		if got := err != nil && strings.Contains(err.Error(), "upload limit"); got != tt.wantErr {
+			t.Errorf("MaxFileSize %d: Validate() = %v, want upload limit error %t", tt.maxFileSize, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateWheels_LocalVersion(t *testing.T) {
+	p := &Publisher{cfg: &Config{Name: "mytool"}, version: "1.2.3+build.5"}
+	pypi, _ := knownRepository("pypi")
+	if err := p.validateWheels(pypi); err == nil || !strings.Contains(err.Error(), "local version") {
+		t.Errorf("validateWheels(pypi) = %v, want a local version error", err)
+	}
+	if err := p.validateWheels(repository{name: "internal"}); err != nil {
+		t.Errorf("validateWheels(internal) = %v, want nil", err)
+	}
+}
+
+func TestBuild_DropsBuildMetadataForPyPI(t *testing.T) {
+	var warnings []string
+	report := publisher.Reporter(func(e publisher.Event) {
+		if e.Kind == publisher.EventWarning {
+			warnings = append(warnings, e.Message)
+		}
+	})
+	rel := publisher.Release{Name: "mytool", Version: "1.2.3+build.5", Artifacts: []config.Artifact{makeWheelArtifact(t, t.TempDir(), "linux", "amd64")}, Report: report}
+
+	p := NewPublisher(&Config{})
+	cleanup, err := p.Build(t.Context(), rel)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+	if got := p.Packages()[0]; !strings.HasPrefix(got, "mytool-1.2.3-") {
+		t.Errorf("wheel = %s, want version 1.2.3", got)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "build metadata") {
+		t.Errorf("warnings = %q, want the dropped build metadata", warnings)
+	}
+
+	if _, err := NewPublisher(&Config{StrictVersion: true}).Build(t.Context(), rel); err == nil {
+		t.Error("Build() with strict versions = nil, want an error")
+	}
+
+	p = NewPublisher(&Config{Repository: "internal"})
+	cleanup, err = p.Build(t.Context(), rel)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+	if got := p.Packages()[0]; !strings.HasPrefix(got, "mytool-1.2.3+build.5-") {
+		t.Errorf("wheel = %s, want the local version kept for a private index", got)
+	}
+}
+
+func TestBuild_SkipsPlainLinuxForPyPI(t *testing.T) {
+	dir := t.TempDir()
+	var warnings []string
+	rel := publisher.Release{
+		Name:      "mytool",
+		Version:   "1.0.0",
+		Artifacts: []config.Artifact{makeWheelArtifact(t, dir, "linux", "riscv64"), makeWheelArtifact(t, dir, "linux", "amd64")},
+		Report: func(e publisher.Event) {
+			if e.Kind == publisher.EventWarning {
+				warnings = append(warnings, e.Message)
+			}
+		},
+	}
+
+	p := NewPublisher(&Config{Repository: "testpypi"})
+	cleanup, err := p.Build(t.Context(), rel)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+	if got := p.Packages(); len(got) != 1 || strings.Contains(got[0], "riscv64") {
+		t.Errorf("Packages() = %v, want only the x86_64 wheel", got)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "linux_riscv64") {
+		t.Errorf("warnings = %q, want the skipped linux_riscv64 wheel", warnings)
+	}
+
+	p = NewPublisher(&Config{Repository: "internal"})
+	cleanup, err = p.Build(t.Context(), rel)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	defer cleanup()
+	if got := p.Packages(); len(got) != 2 {
+		t.Errorf("Packages() = %v, want the linux_riscv64 wheel kept for a private index", got)
+	}
+}
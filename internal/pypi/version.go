@@ -0,0 +1,135 @@
+package pypi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	pep440Re      = regexp.MustCompile(`^\d+(\.\d+)*((a|b|rc)\d+)?(\.post\d+)?(\.dev\d+)?(\+[a-z0-9]+(\.[a-z0-9]+)*)?$`)
+	semverPartsRe = regexp.MustCompile(`^(\d+\.\d+\.\d+)(?:-([0-9A-Za-z.-]+))?(?:\+([0-9A-Za-z.-]+))?$`)
+	preLabelRe    = regexp.MustCompile(`^([a-zA-Z]+)(\d*)$`)
+	numericRe     = regexp.MustCompile(`^\d+$`)
+)
+
+var preReleaseLabels = map[string]string{
+	"alpha":   "a",
+	"a":       "a",
+	"beta":    "b",
+	"b":       "b",
+	"rc":      "rc",
+	"c":       "rc",
+	"pre":     "rc",
+	"preview": "rc",
+	"dev":     ".dev",
+}
+
+// toPyPIVersion converts a semver version to PEP 440. Build metadata becomes
+// a local version, which PyPI and TestPyPI reject, so unless allowLocal is
+// set it is dropped instead.
+func toPyPIVersion(v string, strict, allowLocal bool) (string, []string, error) {
+	out, lossy, err := convertVersion(v)
+	if err != nil {
+		return "", nil, err
+	}
+	if base, local, ok := strings.Cut(out, "+"); ok && !allowLocal {
+		out = base
+		lossy = append(lossy, fmt.Sprintf("build metadata %q dropped, as PyPI doesn't accept local versions", local))
+	}
+
+	if strict && len(lossy) > 0 {
+		return "", nil, fmt.Errorf(
+			"version %q cannot be converted to PEP 440 without losing information: %s",
+			v, strings.Join(lossy, "; "),
+		)
+	}
+	return out, lossy, nil
+}
+
+func convertVersion(v string) (string, []string, error) {
+	if pep440Re.MatchString(v) {
+		return v, nil, nil
+	}
+
+	m := semverPartsRe.FindStringSubmatch(v)
+	if m == nil {
+		return "", nil, fmt.Errorf(
+			"version %q is not valid PEP 440 (required by PyPI)\n"+
+				"examples: 1.0.0, 1.0.0a1, 1.0.0b1, 1.0.0rc1, 1.0.0.dev1",
+			v,
+		)
+	}
+
+	out := m[1]
+	var lossy []string
+	if m[2] != "" {
+		suffix, dropped := preReleaseSuffix(m[2])
+		out += suffix
+		lossy = append(lossy, dropped...)
+	}
+	if m[3] != "" {
+		out += "+" + strings.NewReplacer("-", ".", "_", ".").Replace(strings.ToLower(m[3]))
+	}
+
+	if !pep440Re.MatchString(out) {
+		return "", nil, fmt.Errorf("version %q converted to %q, which is not valid PEP 440", v, out)
+	}
+	return out, lossy, nil
+}
+
+func preReleaseSuffix(pre string) (string, []string) {
+	ids := strings.FieldsFunc(pre, func(r rune) bool { return r == '.' || r == '-' })
+
+	label, num, rest, ok := takePreRelease(ids)
+	if !ok {
+		n := "0"
+		for _, id := range ids {
+			if numericRe.MatchString(id) {
+				n = strings.TrimLeft(id, "0")
+				if n == "" {
+					n = "0"
+				}
+				break
+			}
+		}
+		return ".dev" + n, []string{fmt.Sprintf("unrecognised prerelease %q mapped to a dev release", pre)}
+	}
+
+	suffix := label + num
+	if label != ".dev" {
+		if devLabel, devNum, after, ok := takePreRelease(rest); ok && devLabel == ".dev" {
+			suffix += devLabel + devNum
+			rest = after
+		}
+	}
+
+	if len(rest) > 0 {
+		return suffix, []string{fmt.Sprintf("prerelease identifiers %q dropped", strings.Join(rest, "."))}
+	}
+	return suffix, nil
+}
+
+func takePreRelease(ids []string) (label, num string, rest []string, ok bool) {
+	if len(ids) == 0 {
+		return "", "", nil, false
+	}
+	m := preLabelRe.FindStringSubmatch(ids[0])
+	if m == nil {
+		return "", "", nil, false
+	}
+	label, ok = preReleaseLabels[strings.ToLower(m[1])]
+	if !ok {
+		return "", "", nil, false
+	}
+
+	num, rest = m[2], ids[1:]
+	if num == "" && len(rest) > 0 && numericRe.MatchString(rest[0]) {
+		num, rest = rest[0], rest[1:]
+	}
+	num = strings.TrimLeft(num, "0")
+	if num == "" {
+		num = "0"
+	}
+	return label, num, rest, true
+}
@@ -0,0 +1,164 @@
+package pypi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToPyPIVersion(t *testing.T) {
+	valid := []string{
+		"1.0", "1.0.0", "1.2.3",
+		"1.0.0a1", "1.0.0b2", "1.0.0rc3",
+		"1.0.0.post1", "1.0.0.dev1",
+		"2.0.0a1",
+	}
+	for _, v := range valid {
+		t.Run("valid:"+v, func(t *testing.T) {
+			got, lossy, err := toPyPIVersion(v, true, true)
+			if err != nil {
+				t.Errorf("toPyPIVersion(%q) unexpected error: %v", v, err)
+			}
+			if got != v {
+				t.Errorf("toPyPIVersion(%q) = %q, want %q", v, got, v)
+			}
+			if len(lossy) != 0 {
+				t.Errorf("toPyPIVersion(%q) reported lossy conversion: %v", v, lossy)
+			}
+		})
+	}
+
+	invalid := []string{
+		"v1.0.0",
+		"abc",
+		"",
+		"1.0.0_beta",
+		"1.0.0-",
+	}
+	for _, v := range invalid {
+		t.Run("invalid:"+v, func(t *testing.T) {
+			_, _, err := toPyPIVersion(v, false, true)
+			if err == nil {
+				t.Errorf("toPyPIVersion(%q) expected error, got nil", v)
+			}
+		})
+	}
+}
+
+func TestToPyPIVersion_SemverTranslation(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1.0.0-alpha.1", "1.0.0a1"},
+		{"1.0.0-alpha", "1.0.0a0"},
+		{"1.0.0-beta.2", "1.0.0b2"},
+		{"1.0.0-beta2", "1.0.0b2"},
+		{"1.0.0-BETA.3", "1.0.0b3"},
+		{"1.0.0-rc.1", "1.0.0rc1"},
+		{"1.0.0-rc.01", "1.0.0rc1"},
+		{"1.0.0-pre.4", "1.0.0rc4"},
+		{"1.0.0-preview.4", "1.0.0rc4"},
+		{"1.0.0-dev.7", "1.0.0.dev7"},
+		{"1.0.0-rc.1.dev.2", "1.0.0rc1.dev2"},
+		{"1.0.0+build.5", "1.0.0+build.5"},
+		{"1.0.0-beta.1+Git-Abc123", "1.0.0b1+git.abc123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, lossy, err := toPyPIVersion(tt.input, true, true)
+			if err != nil {
+				t.Fatalf("toPyPIVersion(%q) unexpected error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("toPyPIVersion(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if len(lossy) != 0 {
+				t.Errorf("toPyPIVersion(%q) should be lossless, got notes: %v", tt.input, lossy)
+			}
+		})
+	}
+}
+
+func TestToPyPIVersion_Lossy(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1.0.0-nightly.20240101", "1.0.0.dev20240101"},
+		{"1.0.0-snapshot", "1.0.0.dev0"},
+		{"1.0.0-beta.1.2", "1.0.0b1"},
+		{"1.0.0-rc.1.hotfix", "1.0.0rc1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, lossy, err := toPyPIVersion(tt.input, false, true)
+			if err != nil {
+				t.Fatalf("toPyPIVersion(%q) unexpected error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("toPyPIVersion(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if len(lossy) == 0 {
+				t.Errorf("toPyPIVersion(%q) should report a lossy conversion", tt.input)
+			}
+
+			_, _, err = toPyPIVersion(tt.input, true, true)
+			if err == nil {
+				t.Fatalf("toPyPIVersion(%q, strict) expected error, got nil", tt.input)
+			}
+			if !strings.Contains(err.Error(), "losing information") {
+				t.Errorf("strict error = %q, want mention of losing information", err.Error())
+			}
+		})
+	}
+}
+
+func TestToPyPIVersion_PublicIndex(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1.2.3+build.5", "1.2.3"},
+		{"1.0.0-beta.1+Git-Abc123", "1.0.0b1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, lossy, err := toPyPIVersion(tt.input, false, false)
+			if err != nil {
+				t.Fatalf("toPyPIVersion(%q) unexpected error: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("toPyPIVersion(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if len(lossy) != 1 || !strings.Contains(lossy[0], "local versions") {
+				t.Errorf("toPyPIVersion(%q) notes = %v, want the dropped build metadata", tt.input, lossy)
+			}
+
+			if _, _, err := toPyPIVersion(tt.input, true, false); err == nil || !strings.Contains(err.Error(), "losing information") {
+				t.Errorf("toPyPIVersion(%q, strict) error = %v, want losing information", tt.input, err)
+			}
+		})
+	}
+
+	if got, lossy, err := toPyPIVersion("1.2.3", true, false); err != nil || got != "1.2.3" || len(lossy) != 0 {
+		t.Errorf("toPyPIVersion(1.2.3) = %q, %v, %v", got, lossy, err)
+	}
+}
+
+func TestConfigPublicIndex(t *testing.T) {
+	tests := []struct {
+		cfg  Config
+		want bool
+	}{
+		{Config{}, true},
+		{Config{Repository: "testpypi"}, true},
+		{Config{Repository: "internal"}, false},
+		{Config{RepositoryURL: "https://upload.pypi.org/legacy"}, true},
+		{Config{RepositoryURL: "https://nexus.example.com/repository/pypi/"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.publicIndex(); got != tt.want {
+			t.Errorf("%+v publicIndex() = %t, want %t", tt.cfg, got, tt.want)
+		}
+	}
+}
@@ -5,29 +5,17 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"hash/crc32"
 	"io"
 	"os"
-	"regexp"
+	"path/filepath"
 	"strings"
 
 	"github.com/jacobarthurs/shipbin/internal/config"
 )
 
-var pep440Re = regexp.MustCompile(`^\d+(\.\d+)*((a|b|rc)\d+)?(\.post\d+)?(\.dev\d+)?$`)
-
-func toPyPIVersion(v string) (string, error) {
-	if !pep440Re.MatchString(v) {
-		return "", fmt.Errorf(
-			"version %q is not valid PEP 440 (required by PyPI)\n"+
-				"examples: 1.0.0, 1.0.0a1, 1.0.0b1, 1.0.0rc1, 1.0.0.dev1",
-			v,
-		)
-	}
-	return v, nil
-}
-
 type wheelFile struct {
 	filename        string
 	pkgName         string
@@ -36,38 +24,75 @@ type wheelFile struct {
 	license         string
 	description     string
 	descContentType string
-	data            []byte
+	path            string
+	size            int64
+	sha256          string
 }
 
-func buildWheel(cfg *Config, a config.Artifact) (wheelFile, error) {
+// buildWheel writes the wheel for a into dir, hashing it as it goes, so that
+// neither the binaries nor the wheel are ever held in memory.
+func buildWheel(cfg *Config, a config.Artifact, dir string) (wheelFile, error) {
 	name := strings.NewReplacer("-", "_", ".", "_").Replace(cfg.Name)
-	version, err := toPyPIVersion(cfg.Version)
+	version, _, err := cfg.version()
 	if err != nil {
 		return wheelFile{}, err
 	}
 	wheelTag := a.Mapping.PyPI.WheelTag
+	if wheelTag == "" {
+		return wheelFile{}, fmt.Errorf("no PyPI wheel tag for %s", a.Platform)
+	}
+	if arch := a.Mapping.PyPI.ManylinuxArch; arch != "" {
+		tag, err := manylinuxTag(a, arch, cfg.CompressedTags)
+		switch {
+		case err == nil:
+			wheelTag = tag
+		case !errors.Is(err, errNotELF):
+			return wheelFile{}, err
+		}
+	}
+	if arch := a.Mapping.PyPI.MacOSArch; arch != "" && !a.MinOS.IsZero() {
+		wheelTag = macosTag(a.MinOS, arch)
+	}
 	filename := fmt.Sprintf("%s-%s-py3-none-%s.whl", name, version, wheelTag)
 	distInfo := fmt.Sprintf("%s-%s.dist-info", name, version)
 
-	var buf bytes.Buffer
-	zw := zip.NewWriter(&buf)
+	path := filepath.Join(dir, filename)
+	out, err := os.Create(path)
+	if err != nil {
+		return wheelFile{}, err
+	}
+	defer func() { _ = out.Close() }()
+	hash := sha256.New()
+	counter := &countingWriter{}
+	zw := zip.NewWriter(io.MultiWriter(out, hash, counter))
 
 	record := &strings.Builder{}
 
-	binaryName := cfg.Name
-	if a.Platform.GOOS == "windows" {
-		binaryName += ".exe"
-	}
-	binaryPath := fmt.Sprintf("%s/bin/%s", name, binaryName)
-	binaryData, err := os.ReadFile(a.Path)
-	if err != nil {
-		return wheelFile{}, fmt.Errorf("failed to read binary %s: %w", a.Path, err)
+	for _, exe := range a.Executables {
+		binaryName := exe.Name
+		if binaryName == "" {
+			binaryName = cfg.Name
+		}
+		if a.Platform.GOOS == "windows" {
+			binaryName += ".exe"
+		}
+		binaryPath := fmt.Sprintf("%s/bin/%s", name, binaryName)
+		if err := addPathToZip(zw, binaryPath, exe.Path, 0755, record); err != nil {
+			return wheelFile{}, fmt.Errorf("failed to read binary %s: %w", exe.Path, err)
+		}
 	}
-	if err := addFileToZip(zw, binaryPath, binaryData, 0755, record); err != nil {
-		return wheelFile{}, err
+
+	for _, f := range a.Files {
+		mode := os.FileMode(0644)
+		if info, err := os.Stat(f.Src); err == nil && info.Mode()&0111 != 0 {
+			mode = 0755
+		}
+		if err := addPathToZip(zw, fmt.Sprintf("%s/%s", name, f.Dest), f.Src, mode, record); err != nil {
+			return wheelFile{}, fmt.Errorf("failed to read %s: %w", f.Src, err)
+		}
 	}
 
-	shimData, err := renderShim(cfg.Name)
+	shimData, err := renderShim(cfg.Name, cfg.executables())
 	if err != nil {
 		return wheelFile{}, fmt.Errorf("failed to render shim: %w", err)
 	}
@@ -92,7 +117,7 @@ func buildWheel(cfg *Config, a config.Artifact) (wheelFile, error) {
 		return wheelFile{}, err
 	}
 
-	entryPoints := fmt.Sprintf("[console_scripts]\n%s = %s:main\n", cfg.Name, name)
+	entryPoints := buildEntryPoints(name, cfg.executables())
 	entryPointsPath := fmt.Sprintf("%s/entry_points.txt", distInfo)
 	if err := addFileToZip(zw, entryPointsPath, []byte(entryPoints), 0644, record); err != nil {
 		return wheelFile{}, err
@@ -107,6 +132,9 @@ func buildWheel(cfg *Config, a config.Artifact) (wheelFile, error) {
 	if err := zw.Close(); err != nil {
 		return wheelFile{}, fmt.Errorf("failed to close wheel zip: %w", err)
 	}
+	if err := out.Close(); err != nil {
+		return wheelFile{}, fmt.Errorf("failed to write %s: %w", path, err)
+	}
 
 	return wheelFile{
 		filename:        filename,
@@ -116,38 +144,81 @@ func buildWheel(cfg *Config, a config.Artifact) (wheelFile, error) {
 		license:         cfg.License,
 		description:     readmeContent,
 		descContentType: contentType,
-		data:            buf.Bytes(),
+		path:            path,
+		size:            counter.n,
+		sha256:          hex.EncodeToString(hash.Sum(nil)),
 	}, nil
 }
 
 func addFileToZip(zw *zip.Writer, path string, data []byte, mode os.FileMode, record *strings.Builder) error {
+	return addToZip(zw, path, bytes.NewReader(data), mode, record)
+}
+
+func addPathToZip(zw *zip.Writer, path, src string, mode os.FileMode, record *strings.Builder) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	return addToZip(zw, path, f, mode, record)
+}
+
+// addToZip stores r uncompressed. The zip writer computes the CRC and sizes
+// as the entry is written and records them in a data descriptor.
+func addToZip(zw *zip.Writer, path string, r io.Reader, mode os.FileMode, record *strings.Builder) error {
 	header := &zip.FileHeader{
-		Name:               path,
-		Method:             zip.Store,
-		CRC32:              crc32.ChecksumIEEE(data),
-		CompressedSize64:   uint64(len(data)),
-		UncompressedSize64: uint64(len(data)),
+		Name:   path,
+		Method: zip.Store,
 	}
 	header.SetMode(mode)
 	header.ExternalAttrs = uint32(mode) << 16
 
-	w, err := zw.CreateRaw(header)
+	w, err := zw.CreateHeader(header)
 	if err != nil {
 		return fmt.Errorf("failed to create zip entry %s: %w", path, err)
 	}
-	if _, err := w.Write(data); err != nil {
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, hash), r)
+	if err != nil {
 		return fmt.Errorf("failed to write zip entry %s: %w", path, err)
 	}
 
 	if record != nil {
-		hash := sha256.Sum256(data)
-		encoded := base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(hash[:])
-		fmt.Fprintf(record, "%s,sha256=%s,%d\n", path, encoded, len(data))
+		encoded := base64.URLEncoding.WithPadding(base64.NoPadding).EncodeToString(hash.Sum(nil))
+		fmt.Fprintf(record, "%s,sha256=%s,%d\n", path, encoded, n)
 	}
 
 	return nil
 }
 
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = out.Close() }()
+
+	if _, err = io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
 func buildMetadata(name, version, summary, license, readmeContent, contentType string) string {
 	var sb strings.Builder
 	fmt.Fprintf(&sb, "Metadata-Version: 2.1\n")
@@ -187,14 +258,21 @@ func readReadme(path string) (content, contentType string, err error) {
 	return string(data), contentType, nil
 }
 
-func buildWheelMeta(platformTag string) string {
-	return fmt.Sprintf(`Wheel-Version: 1.0
-Generator: shipbin
-Root-Is-Purelib: false
-Tag: py3-none-%s
-`, platformTag)
+func buildEntryPoints(module string, executables []string) string {
+	var sb strings.Builder
+	sb.WriteString("[console_scripts]\n")
+	funcs := entryPointFuncs(executables)
+	for i, exe := range executables {
+		fmt.Fprintf(&sb, "%s = %s:%s\n", exe, module, funcs[i])
+	}
+	return sb.String()
 }
 
-func (w wheelFile) reader() io.Reader {
-	return bytes.NewReader(w.data)
+func buildWheelMeta(platformTag string) string {
+	var sb strings.Builder
+	sb.WriteString("Wheel-Version: 1.0\nGenerator: shipbin\nRoot-Is-Purelib: false\n")
+	for _, tag := range strings.Split(platformTag, ".") {
+		fmt.Fprintf(&sb, "Tag: py3-none-%s\n", tag)
+	}
+	return sb.String()
 }
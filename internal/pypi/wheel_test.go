@@ -3,8 +3,12 @@ package pypi
 import (
 	"archive/zip"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -21,42 +25,6 @@ func checkContains(t *testing.T, s string, substrs ...string) {
 	}
 }
 
-func TestToPyPIVersion(t *testing.T) {
-	valid := []string{
-		"1.0", "1.0.0", "1.2.3",
-		"1.0.0a1", "1.0.0b2", "1.0.0rc3",
-		"1.0.0.post1", "1.0.0.dev1",
-		"2.0.0a1",
-	}
-	for _, v := range valid {
-		t.Run("valid:"+v, func(t *testing.T) {
-			got, err := toPyPIVersion(v)
-			if err != nil {
-				t.Errorf("toPyPIVersion(%q) unexpected error: %v", v, err)
-			}
-			if got != v {
-				t.Errorf("toPyPIVersion(%q) = %q, want %q", v, got, v)
-			}
-		})
-	}
-
-	invalid := []string{
-		"1.0.0-alpha",
-		"1.0.0-beta.1",
-		"v1.0.0",
-		"abc",
-		"",
-	}
-	for _, v := range invalid {
-		t.Run("invalid:"+v, func(t *testing.T) {
-			_, err := toPyPIVersion(v)
-			if err == nil {
-				t.Errorf("toPyPIVersion(%q) expected error, got nil", v)
-			}
-		})
-	}
-}
-
 func TestReadReadme_ContentTypes(t *testing.T) {
 	dir := t.TempDir()
 	tests := []struct {
@@ -145,20 +113,21 @@ func TestBuildMetadata_MinimalFields(t *testing.T) {
 }
 
 func TestBuildWheelMeta(t *testing.T) {
-	tag := "manylinux_2_17_x86_64.manylinux2014_x86_64"
-	meta := buildWheelMeta(tag)
+	meta := buildWheelMeta("manylinux_2_17_x86_64.manylinux2014_x86_64")
 
 	checkContains(t, meta,
 		"Wheel-Version: 1.0",
 		"Generator: shipbin",
 		"Root-Is-Purelib: false",
-		"Tag: py3-none-"+tag,
+		"Tag: py3-none-manylinux_2_17_x86_64\n",
+		"Tag: py3-none-manylinux2014_x86_64\n",
 	)
 }
 
 func makeWheelArtifact(t *testing.T, dir, goos, goarch string) config.Artifact {
 	t.Helper()
-	m, err := platforms.Lookup(goos, goarch)
+	p := platforms.Platform{GOOS: goos, GOARCH: goarch}
+	m, err := platforms.Lookup(p)
 	if err != nil {
 		t.Fatalf("platforms.Lookup: %v", err)
 	}
@@ -168,9 +137,66 @@ func makeWheelArtifact(t *testing.T, dir, goos, goarch string) config.Artifact {
 		t.Fatalf("failed to write test binary: %v", err)
 	}
 	return config.Artifact{
-		Platform: platforms.Platform{GOOS: goos, GOARCH: goarch},
-		Mapping:  m,
-		Path:     path,
+		Platform:    p,
+		Mapping:     m,
+		Executables: []config.Executable{{Path: path}},
+	}
+}
+
+func wheelData(t *testing.T, w wheelFile) []byte {
+	t.Helper()
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		t.Fatalf("failed to read wheel: %v", err)
+	}
+	return data
+}
+
+// withData stores data as the contents of w.
+func withData(t *testing.T, w wheelFile, data []byte) wheelFile {
+	t.Helper()
+	w.path = filepath.Join(t.TempDir(), w.filename)
+	if err := os.WriteFile(w.path, data, 0644); err != nil {
+		t.Fatalf("failed to write wheel: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	w.size, w.sha256 = int64(len(data)), hex.EncodeToString(sum[:])
+	return w
+}
+
+func TestBuildWheel_StreamsLargeBinaries(t *testing.T) {
+	dir := t.TempDir()
+	a := makeWheelArtifact(t, dir, "linux", "amd64")
+	const size = 64 << 20
+	if err := os.Truncate(a.Executables[0].Path, size); err != nil {
+		t.Fatal(err)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	wf, err := buildWheel(&Config{Name: "mytool", Version: "1.0.0"}, a, t.TempDir())
+	if err != nil {
+		t.Fatalf("buildWheel: %v", err)
+	}
+	runtime.ReadMemStats(&after)
+
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > size/8 {
+		t.Errorf("building a %d MB wheel allocated %d MB", size>>20, alloc>>20)
+	}
+	if wf.size < size {
+		t.Errorf("size = %d, want over %d", wf.size, size)
+	}
+	f, err := os.Open(wf.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != wf.sha256 {
+		t.Errorf("sha256 = %s, want %s", wf.sha256, got)
 	}
 }
 
@@ -185,7 +211,7 @@ func TestBuildWheel_ZipStructure(t *testing.T) {
 		License: "MIT",
 	}
 
-	wf, err := buildWheel(cfg, a)
+	wf, err := buildWheel(cfg, a, t.TempDir())
 	if err != nil {
 		t.Fatalf("buildWheel: %v", err)
 	}
@@ -198,7 +224,7 @@ func TestBuildWheel_ZipStructure(t *testing.T) {
 		t.Errorf("version = %q, want %q", wf.version, "1.0.0")
 	}
 
-	zr, err := zip.NewReader(bytes.NewReader(wf.data), int64(len(wf.data)))
+	zr, err := zip.NewReader(bytes.NewReader(wheelData(t, wf)), wf.size)
 	if err != nil {
 		t.Fatalf("wheel is not a valid ZIP: %v", err)
 	}
@@ -229,12 +255,12 @@ func TestBuildWheel_WindowsBinaryHasExeSuffix(t *testing.T) {
 
 	cfg := &Config{Name: "mytool", Version: "1.0.0"}
 
-	wf, err := buildWheel(cfg, a)
+	wf, err := buildWheel(cfg, a, t.TempDir())
 	if err != nil {
 		t.Fatalf("buildWheel: %v", err)
 	}
 
-	zr, err := zip.NewReader(bytes.NewReader(wf.data), int64(len(wf.data)))
+	zr, err := zip.NewReader(bytes.NewReader(wheelData(t, wf)), wf.size)
 	if err != nil {
 		t.Fatalf("not a valid ZIP: %v", err)
 	}
@@ -257,15 +283,57 @@ func TestBuildWheel_InvalidPEP440Version(t *testing.T) {
 
 	cfg := &Config{
 		Name:    "mytool",
-		Version: "1.0.0-beta",
+		Version: "1.0.0_beta",
 	}
 
-	_, err := buildWheel(cfg, a)
+	_, err := buildWheel(cfg, a, t.TempDir())
 	if err == nil {
 		t.Fatal("expected error for non-PEP 440 version, got nil")
 	}
 }
 
+func TestBuildWheel_TranslatesSemverPrerelease(t *testing.T) {
+	dir := t.TempDir()
+	a := makeWheelArtifact(t, dir, "linux", "amd64")
+
+	cfg := &Config{Name: "mytool", Version: "1.0.0-beta.2"}
+
+	wf, err := buildWheel(cfg, a, t.TempDir())
+	if err != nil {
+		t.Fatalf("buildWheel: %v", err)
+	}
+	if wf.version != "1.0.0b2" {
+		t.Errorf("version = %q, want %q", wf.version, "1.0.0b2")
+	}
+	if !strings.HasPrefix(wf.filename, "mytool-1.0.0b2-") {
+		t.Errorf("filename = %q, want PEP 440 version in name", wf.filename)
+	}
+}
+
+func TestBuildWheel_StrictVersionRejectsLossy(t *testing.T) {
+	dir := t.TempDir()
+	a := makeWheelArtifact(t, dir, "linux", "amd64")
+
+	cfg := &Config{Name: "mytool", Version: "1.0.0-nightly.5", StrictVersion: true}
+
+	if _, err := buildWheel(cfg, a, t.TempDir()); err == nil {
+		t.Fatal("expected error for lossy conversion in strict mode, got nil")
+	}
+}
+
+func TestBuildWheel_NoWheelTag(t *testing.T) {
+	dir := t.TempDir()
+	a := makeWheelArtifact(t, dir, "freebsd", "amd64")
+
+	_, err := buildWheel(&Config{Name: "mytool", Version: "1.0.0"}, a, t.TempDir())
+	if err == nil {
+		t.Fatal("expected error for platform without a wheel tag, got nil")
+	}
+	if !strings.Contains(err.Error(), "freebsd/amd64") {
+		t.Errorf("error should name the platform, got: %v", err)
+	}
+}
+
 func TestBuildWheel_NameNormalization(t *testing.T) {
 	dir := t.TempDir()
 	a := makeWheelArtifact(t, dir, "linux", "amd64")
@@ -275,7 +343,7 @@ func TestBuildWheel_NameNormalization(t *testing.T) {
 		Version: "1.0.0",
 	}
 
-	wf, err := buildWheel(cfg, a)
+	wf, err := buildWheel(cfg, a, t.TempDir())
 	if err != nil {
 		t.Fatalf("buildWheel: %v", err)
 	}
@@ -294,12 +362,12 @@ func TestBuildWheel_RecordContainsHashes(t *testing.T) {
 
 	cfg := &Config{Name: "mytool", Version: "1.0.0"}
 
-	wf, err := buildWheel(cfg, a)
+	wf, err := buildWheel(cfg, a, t.TempDir())
 	if err != nil {
 		t.Fatalf("buildWheel: %v", err)
 	}
 
-	zr, err := zip.NewReader(bytes.NewReader(wf.data), int64(len(wf.data)))
+	zr, err := zip.NewReader(bytes.NewReader(wheelData(t, wf)), wf.size)
 	if err != nil {
 		t.Fatalf("not a valid ZIP: %v", err)
 	}
@@ -334,7 +402,7 @@ func TestBuildWheel_DotNameNormalization(t *testing.T) {
 		Version: "1.0.0",
 	}
 
-	wf, err := buildWheel(cfg, a)
+	wf, err := buildWheel(cfg, a, t.TempDir())
 	if err != nil {
 		t.Fatalf("buildWheel: %v", err)
 	}
@@ -346,7 +414,7 @@ func TestBuildWheel_DotNameNormalization(t *testing.T) {
 		t.Errorf("wheel filename should use underscores for dots, got %q", wf.filename)
 	}
 
-	zr, err := zip.NewReader(bytes.NewReader(wf.data), int64(len(wf.data)))
+	zr, err := zip.NewReader(bytes.NewReader(wheelData(t, wf)), wf.size)
 	if err != nil {
 		t.Fatalf("not a valid ZIP: %v", err)
 	}
@@ -368,12 +436,12 @@ func TestBuildWheel_ZipFileModes(t *testing.T) {
 
 	cfg := &Config{Name: "mytool", Version: "1.0.0"}
 
-	wf, err := buildWheel(cfg, a)
+	wf, err := buildWheel(cfg, a, t.TempDir())
 	if err != nil {
 		t.Fatalf("buildWheel: %v", err)
 	}
 
-	zr, err := zip.NewReader(bytes.NewReader(wf.data), int64(len(wf.data)))
+	zr, err := zip.NewReader(bytes.NewReader(wheelData(t, wf)), wf.size)
 	if err != nil {
 		t.Fatalf("not a valid ZIP: %v", err)
 	}
@@ -392,3 +460,115 @@ func TestBuildWheel_ZipFileModes(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildWheel_MultipleExecutables(t *testing.T) {
+	dir := t.TempDir()
+	a := makeWheelArtifact(t, dir, "linux", "amd64")
+	a.Executables[0].Name = "mytool"
+	lspPath := filepath.Join(dir, "lsp")
+	if err := os.WriteFile(lspPath, []byte("lsp binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	a.Executables = append(a.Executables, config.Executable{Name: "mytool-lsp", Path: lspPath})
+
+	cfg := &Config{Name: "mytool", Executables: []string{"mytool", "mytool-lsp"}, Version: "1.0.0"}
+
+	wf, err := buildWheel(cfg, a, t.TempDir())
+	if err != nil {
+		t.Fatalf("buildWheel: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(wheelData(t, wf)), wf.size)
+	if err != nil {
+		t.Fatalf("not a valid ZIP: %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(rc); err != nil {
+			t.Fatal(err)
+		}
+		_ = rc.Close()
+		files[f.Name] = buf.String()
+	}
+
+	for _, name := range []string{"mytool/bin/mytool", "mytool/bin/mytool-lsp"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("wheel missing %s", name)
+		}
+	}
+	checkContains(t, files["mytool-1.0.0.dist-info/entry_points.txt"],
+		"mytool = mytool:main\n",
+		"mytool-lsp = mytool:main_mytool_lsp\n",
+	)
+	checkContains(t, files["mytool/__init__.py"], "def main_mytool_lsp():")
+	checkContains(t, files["mytool-1.0.0.dist-info/RECORD"], "mytool/bin/mytool-lsp,sha256=")
+}
+
+func TestBuildEntryPoints(t *testing.T) {
+	got := buildEntryPoints("my_tool", []string{"my-tool", "my-tool.lsp"})
+	want := "[console_scripts]\nmy-tool = my_tool:main\nmy-tool.lsp = my_tool:main_my_tool_lsp\n"
+	if got != want {
+		t.Errorf("buildEntryPoints() = %q, want %q", got, want)
+	}
+}
+
+func TestBuildWheel_ExtraFiles(t *testing.T) {
+	dir := t.TempDir()
+	a := makeWheelArtifact(t, dir, "linux", "amd64")
+	license := filepath.Join(dir, "LICENSE")
+	if err := os.WriteFile(license, []byte("MIT"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lib := filepath.Join(dir, "libfoo.so")
+	if err := os.WriteFile(lib, []byte("lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	a.Files = []config.ExtraFile{
+		{Src: license, Dest: "share/LICENSE"},
+		{Src: lib, Dest: "bin/libfoo.so"},
+	}
+
+	cfg := &Config{Name: "mytool", Version: "1.0.0"}
+
+	wf, err := buildWheel(cfg, a, t.TempDir())
+	if err != nil {
+		t.Fatalf("buildWheel: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(wheelData(t, wf)), wf.size)
+	if err != nil {
+		t.Fatalf("not a valid ZIP: %v", err)
+	}
+
+	modes := make(map[string]os.FileMode)
+	var record string
+	for _, f := range zr.File {
+		modes[f.Name] = f.Mode().Perm()
+		if f.Name == "mytool-1.0.0.dist-info/RECORD" {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if _, err := buf.ReadFrom(rc); err != nil {
+				t.Fatal(err)
+			}
+			_ = rc.Close()
+			record = buf.String()
+		}
+	}
+
+	if mode, ok := modes["mytool/share/LICENSE"]; !ok || mode != 0644 {
+		t.Errorf("mytool/share/LICENSE present=%v mode=%04o, want 0644", ok, mode)
+	}
+	if mode, ok := modes["mytool/bin/libfoo.so"]; !ok || mode != 0755 {
+		t.Errorf("mytool/bin/libfoo.so present=%v mode=%04o, want 0755", ok, mode)
+	}
+	checkContains(t, record, "mytool/share/LICENSE,sha256=", "mytool/bin/libfoo.so,sha256=")
+}
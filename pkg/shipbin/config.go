@@ -0,0 +1,146 @@
+package shipbin
+
+import (
+	"time"
+
+	"github.com/jacobarthurs/shipbin/internal/httpclient"
+	"github.com/jacobarthurs/shipbin/internal/publisher"
+)
+
+// Config describes a release. Its fields mirror the CLI flags of the same
+// names.
+type Config struct {
+	Name string
+	// Executables defaults to Name.
+	Executables []string
+	// Version defaults to the version in GoReleaserDist, then to the exact
+	// git tag of the working directory.
+	Version string
+	Summary string
+	License string
+	Readme  string
+
+	// Artifacts are os/arch:path entries. When empty, artifacts come from
+	// GoReleaserDist, then from the artifacts list of ConfigFile.
+	Artifacts      []string
+	GoReleaserDist string
+	// Includes are [os/arch:]path[=dest] entries. When empty, the include
+	// list of ConfigFile is used.
+	Includes []string
+	// ConfigFile is a shipbin.yaml or shipbin.toml. Only its artifacts and
+	// include lists are used, with paths relative to the file.
+	ConfigFile    string
+	SkipArchCheck bool
+
+	DryRun bool
+	// JournalDir, when set, is where Publish records each package it
+	// uploads, so that running it again after an interruption resumes where
+	// it stopped. The journal is removed once every target succeeds.
+	JournalDir string
+	// Concurrency is how many packages of a target are uploaded at once.
+	// Zero means the default of 1; it must not be negative.
+	Concurrency int
+	// Retries is how many times a registry request that fails with a
+	// network error, a 408, a 429 or a 5xx is retried. It defaults to 3;
+	// a negative value disables retries.
+	Retries int
+	// RequestTimeout bounds each attempt of a registry request. It defaults
+	// to 5 minutes.
+	RequestTimeout time.Duration
+	// Targets are npm, pypi, or plugin names.
+	Targets []string
+	Npm     NpmConfig
+	PyPI    PyPIConfig
+	// Plugins holds the options passed to each plugin, keyed by name.
+	Plugins map[string]map[string]any
+
+	// OnEvent, when set, receives progress as the release is built and
+	// published. It is never called concurrently, and events arrive in
+	// package order even when packages are uploaded in parallel.
+	OnEvent func(Event)
+}
+
+type NpmConfig struct {
+	Org string
+	// Tag defaults to latest.
+	Tag string
+	// Provenance is only used when set: unlike the CLI, the library doesn't
+	// turn it on in GitHub Actions.
+	Provenance bool
+	UseCLI     bool
+	// Registry defaults to the one .npmrc configures for each package.
+	Registry string
+	// Access is public (the default) or restricted.
+	Access string
+}
+
+type PyPIConfig struct {
+	StrictVersion  bool
+	CompressedTags bool
+	SplitUniversal bool
+	// Repository is pypi (the default), testpypi, or a ~/.pypirc section.
+	Repository    string
+	RepositoryURL string
+	// MaxFileSize is the largest wheel, in bytes, to upload. Zero means the
+	// default: 100 MB on PyPI and TestPyPI, which raise it per project on
+	// request, and no limit elsewhere. It must not be negative.
+	MaxFileSize int64
+}
+
+// Event reports progress. Message is a human-readable line; Package names
+// the package or file for EventPublishing, EventPublished and EventSkipped.
+type Event = publisher.Event
+
+type EventKind = publisher.EventKind
+
+const (
+	EventInfo       = publisher.EventInfo
+	EventWarning    = publisher.EventWarning
+	EventPublishing = publisher.EventPublishing
+	EventPublished  = publisher.EventPublished
+	EventSkipped    = publisher.EventSkipped
+)
+
+// Result reports what Publish did for one target. Skipped lists the
+// packages that were already published with the same contents.
+type Result struct {
+	Target    string
+	Packages  []string
+	Published []string
+	Skipped   []string
+	Err       error
+}
+
+// PackedFile is a package file written by Pack. Path is relative to the
+// output directory.
+type PackedFile struct {
+	Target  string `json:"target"`
+	Package string `json:"package"`
+	Path    string `json:"file"`
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+}
+
+func (c *Config) executables() []string {
+	if len(c.Executables) == 0 {
+		return []string{c.Name}
+	}
+	return c.Executables
+}
+
+func (c *Config) retries() int {
+	switch {
+	case c.Retries < 0:
+		return 0
+	case c.Retries == 0:
+		return httpclient.DefaultRetries
+	}
+	return c.Retries
+}
+
+func (c *Config) requestTimeout() time.Duration {
+	if c.RequestTimeout == 0 {
+		return httpclient.DefaultTimeout
+	}
+	return c.RequestTimeout
+}
@@ -0,0 +1,95 @@
+package shipbin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ManifestFile is the name of the manifest Pack writes next to the packages.
+const ManifestFile = "manifest.json"
+
+type manifest struct {
+	Name    string       `json:"name"`
+	Version string       `json:"version"`
+	Files   []PackedFile `json:"files"`
+}
+
+// Pack builds the packages for every target in cfg.Targets and writes them
+// to outDir instead of publishing them: a .tgz per npm package and a .whl per
+// wheel, ready for npm install or pip install. It also writes a manifest
+// listing every file with its size and SHA-256. No credentials are needed.
+func Pack(ctx context.Context, cfg Config, outDir string) ([]PackedFile, error) {
+	cfg.JournalDir = ""
+	pubs, rel, cleanup, err := build(ctx, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", outDir, err)
+	}
+
+	var files []PackedFile
+	for _, p := range pubs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		packed, err := p.Pack(ctx, outDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range packed {
+			file, err := describeFile(outDir, p.Name(), f.Package, f.Path)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, file)
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest{Name: cfg.Name, Version: rel.Version, Files: files}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(outDir, ManifestFile), append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return files, nil
+}
+
+func describeFile(outDir, target, pkg, path string) (PackedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return PackedFile{}, fmt.Errorf("%s: %w", target, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return PackedFile{}, fmt.Errorf("%s: failed to read %s: %w", target, path, err)
+	}
+
+	rel := path
+	absDir, dirErr := filepath.Abs(outDir)
+	absPath, pathErr := filepath.Abs(path)
+	if dirErr == nil && pathErr == nil {
+		if r, err := filepath.Rel(absDir, absPath); err == nil {
+			rel = r
+		}
+	}
+	return PackedFile{
+		Target:  target,
+		Package: pkg,
+		Path:    filepath.ToSlash(rel),
+		Size:    size,
+		SHA256:  hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
@@ -0,0 +1,247 @@
+// Package shipbin builds npm packages and Python wheels from pre-built
+// binaries and publishes them, as the shipbin CLI does.
+package shipbin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/jacobarthurs/shipbin/internal/config"
+	"github.com/jacobarthurs/shipbin/internal/npm"
+	"github.com/jacobarthurs/shipbin/internal/publisher"
+	"github.com/jacobarthurs/shipbin/internal/pypi"
+)
+
+// Publish builds the packages for every target in cfg.Targets, validates them
+// all, and then publishes each target in order. Nothing is uploaded unless
+// every target builds and validates. Once publishing starts, a failing target
+// doesn't stop the ones after it: the returned results say what each target
+// published, and the error joins the failures.
+//
+// Packages already in the registry with the same contents are skipped, so a
+// failed release can be published again; different contents are an error.
+// Canceling ctx aborts the uploads in flight and starts no new ones, and the
+// results still say what each target published.
+func Publish(ctx context.Context, cfg Config) ([]Result, error) {
+	pubs, rel, cleanup, err := build(ctx, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	var errs []error
+	for _, p := range pubs {
+		if err := p.Validate(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	results := make([]Result, len(pubs))
+	var failed []error
+	for i, p := range pubs {
+		err := ctx.Err()
+		if err == nil {
+			err = p.Publish(ctx)
+		}
+		results[i] = Result{Target: p.Name(), Packages: p.Packages(), Published: p.Published(), Skipped: p.Skipped(), Err: err}
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+	if len(failed) == 0 && rel.Journal != nil {
+		if err := rel.Journal.Remove(); err != nil {
+			rel.Report.Warnf("journal", "failed to remove %s: %v", rel.Journal.Path(), err)
+		}
+	}
+	return results, errors.Join(failed...)
+}
+
+func build(ctx context.Context, cfg *Config) ([]publisher.Publisher, publisher.Release, func(), error) {
+	if cfg.Name == "" {
+		return nil, publisher.Release{}, nil, fmt.Errorf("name is required")
+	}
+	if len(cfg.Targets) == 0 {
+		return nil, publisher.Release{}, nil, fmt.Errorf("no targets given: use one or more of npm, pypi, or a plugin name")
+	}
+	if cfg.Concurrency < 0 {
+		return nil, publisher.Release{}, nil, fmt.Errorf("invalid concurrency %d: must not be negative", cfg.Concurrency)
+	}
+	if cfg.PyPI.MaxFileSize < 0 {
+		return nil, publisher.Release{}, nil, fmt.Errorf("invalid PyPI max file size %d: must not be negative", cfg.PyPI.MaxFileSize)
+	}
+	if cfg.RequestTimeout < 0 {
+		return nil, publisher.Release{}, nil, fmt.Errorf("invalid request timeout %s: must be positive", cfg.RequestTimeout)
+	}
+
+	reg := newRegistry(cfg)
+	var pubs []publisher.Publisher
+	var seen []string
+	for _, t := range cfg.Targets {
+		if slices.Contains(seen, t) {
+			continue
+		}
+		seen = append(seen, t)
+		p, err := reg.New(t)
+		if err != nil {
+			return nil, publisher.Release{}, nil, err
+		}
+		pubs = append(pubs, p)
+	}
+
+	version, artifacts, cleanup, err := resolveRelease(cfg)
+	if err != nil {
+		return nil, publisher.Release{}, nil, err
+	}
+	cleanups := []func(){cleanup}
+	cleanupAll := func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}
+
+	rel := publisher.Release{
+		Name:        cfg.Name,
+		Executables: cfg.executables(),
+		Version:     version,
+		Summary:     cfg.Summary,
+		License:     cfg.License,
+		Readme:      cfg.Readme,
+		Artifacts:   artifacts,
+		DryRun:      cfg.DryRun,
+		Concurrency: cfg.Concurrency,
+		Retries:     cfg.retries(),
+		Timeout:     cfg.requestTimeout(),
+		Report:      cfg.OnEvent,
+	}
+	if cfg.JournalDir != "" && !cfg.DryRun {
+		j, err := publisher.OpenJournal(cfg.JournalDir, cfg.Name, version)
+		if err != nil {
+			cleanupAll()
+			return nil, publisher.Release{}, nil, err
+		}
+		if n := j.Len(); n > 0 {
+			rel.Report.Infof("journal", "resuming from %s: %d packages already published", j.Path(), n)
+		}
+		rel.Journal = j
+	}
+	for _, p := range pubs {
+		if err := ctx.Err(); err != nil {
+			cleanupAll()
+			return nil, publisher.Release{}, nil, err
+		}
+		buildCleanup, err := p.Build(ctx, rel)
+		if err != nil {
+			cleanupAll()
+			return nil, publisher.Release{}, nil, err
+		}
+		cleanups = append(cleanups, buildCleanup)
+	}
+	return pubs, rel, cleanupAll, nil
+}
+
+func newRegistry(cfg *Config) *publisher.Registry {
+	reg := publisher.NewRegistry()
+	reg.Register("npm", func() (publisher.Publisher, error) {
+		c := cfg.Npm
+		if c.Org == "" {
+			return nil, fmt.Errorf("npm: an org is required")
+		}
+		if c.Tag == "" {
+			c.Tag = "latest"
+		}
+		if c.Access == "" {
+			c.Access = "public"
+		}
+		if c.Access != "public" && c.Access != "restricted" {
+			return nil, fmt.Errorf("npm: invalid access %q: must be public or restricted", c.Access)
+		}
+		return npm.NewPublisher(&npm.Config{
+			Org:        c.Org,
+			Tag:        c.Tag,
+			Provenance: c.Provenance,
+			UseCLI:     c.UseCLI,
+			Registry:   c.Registry,
+			Access:     c.Access,
+		}), nil
+	})
+	reg.Register("pypi", func() (publisher.Publisher, error) {
+		c := cfg.PyPI
+		return pypi.NewPublisher(&pypi.Config{
+			StrictVersion:  c.StrictVersion,
+			CompressedTags: c.CompressedTags,
+			SplitUniversal: c.SplitUniversal,
+			Repository:     c.Repository,
+			RepositoryURL:  c.RepositoryURL,
+			MaxFileSize:    c.MaxFileSize,
+		}), nil
+	})
+	for name, options := range cfg.Plugins {
+		reg.ConfigurePlugin(name, options)
+	}
+	return reg
+}
+
+func resolveRelease(cfg *Config) (string, []config.Artifact, func(), error) {
+	var file *config.File
+	if cfg.ConfigFile != "" {
+		f, err := config.LoadFile(cfg.ConfigFile)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		file = f
+	}
+
+	version := cfg.Version
+	includes, err := resolveIncludes(cfg, file)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	opts := config.ParseOptions{Executables: cfg.executables(), Includes: includes, SkipArchCheck: cfg.SkipArchCheck}
+	var artifacts []config.Artifact
+	var cleanup func()
+
+	switch {
+	case len(cfg.Artifacts) > 0:
+		artifacts, cleanup, err = config.ParseArtifacts(cfg.Artifacts, opts)
+	case cfg.GoReleaserDist != "":
+		dist, distErr := config.LoadGoReleaser(cfg.GoReleaserDist, opts.Executables)
+		if distErr != nil {
+			return "", nil, nil, distErr
+		}
+		for _, p := range dist.Skipped {
+			publisher.Reporter(cfg.OnEvent).Infof("goreleaser", "skipping unsupported platform %s", p)
+		}
+		if version == "" {
+			version = dist.Version
+		}
+		artifacts, cleanup, err = dist.ParseArtifacts(opts)
+	case file != nil && len(file.Artifacts) > 0:
+		artifacts, cleanup, err = file.ParseArtifacts(opts)
+	default:
+		return "", nil, nil, fmt.Errorf("no artifacts given")
+	}
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	version, err = config.ResolveVersion(version)
+	if err != nil {
+		cleanup()
+		return "", nil, nil, err
+	}
+
+	return version, artifacts, cleanup, nil
+}
+
+func resolveIncludes(cfg *Config, file *config.File) ([]config.Include, error) {
+	if len(cfg.Includes) == 0 && file != nil {
+		return file.ParseIncludes()
+	}
+	return config.ParseIncludes(cfg.Includes)
+}
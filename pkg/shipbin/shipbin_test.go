@@ -0,0 +1,192 @@
+package shipbin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeBinary(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mytool")
+	if err := os.WriteFile(path, []byte("fake binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func isolate(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("NPM_CONFIG_USERCONFIG", "missing")
+	t.Setenv("NODE_AUTH_TOKEN", "")
+	t.Setenv("PYPI_TOKEN", "")
+	t.Setenv("PYPI_PASSWORD", "")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "")
+}
+
+func TestPublish_DryRun(t *testing.T) {
+	isolate(t)
+	var events []Event
+	cfg := Config{
+		Name:          "mytool",
+		Version:       "1.2.3",
+		Artifacts:     []string{"linux/amd64:" + writeBinary(t)},
+		SkipArchCheck: true,
+		DryRun:        true,
+		Targets:       []string{"npm", "pypi", "npm"},
+		Npm:           NpmConfig{Org: "myorg"},
+		OnEvent:       func(e Event) { events = append(events, e) },
+	}
+
+	results, err := Publish(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if len(results) != 2 || results[0].Target != "npm" || results[1].Target != "pypi" {
+		t.Fatalf("results = %+v, want npm then pypi", results)
+	}
+	if want := []string{"@myorg/mytool-linux-x64", "mytool"}; !slices.Equal(results[0].Packages, want) {
+		t.Errorf("npm packages = %v, want %v", results[0].Packages, want)
+	}
+	if want := []string{"mytool-1.2.3-py3-none-manylinux_2_17_x86_64.manylinux2014_x86_64.whl"}; !slices.Equal(results[1].Packages, want) {
+		t.Errorf("pypi packages = %v, want %v", results[1].Packages, want)
+	}
+	if len(results[0].Published) != 0 || len(results[1].Published) != 0 {
+		t.Errorf("dry run published %v and %v", results[0].Published, results[1].Published)
+	}
+
+	var publishing []string
+	for _, e := range events {
+		if e.Kind == EventPublishing {
+			publishing = append(publishing, e.Target+" "+e.Package)
+		}
+	}
+	want := []string{"npm @myorg/mytool-linux-x64", "npm mytool", "pypi " + results[1].Packages[0]}
+	if !slices.Equal(publishing, want) {
+		t.Errorf("publishing events = %v, want %v", publishing, want)
+	}
+}
+
+func TestPublish_ValidatesEveryTarget(t *testing.T) {
+	isolate(t)
+	cfg := Config{
+		Name:          "mytool",
+		Version:       "1.2.3",
+		Artifacts:     []string{"linux/amd64:" + writeBinary(t)},
+		SkipArchCheck: true,
+		Targets:       []string{"npm", "pypi"},
+		Npm:           NpmConfig{Org: "myorg"},
+	}
+
+	results, err := Publish(context.Background(), cfg)
+	if results != nil {
+		t.Errorf("results = %+v, want none when validation fails", results)
+	}
+	if err == nil || !strings.Contains(err.Error(), "NODE_AUTH_TOKEN") || !strings.Contains(err.Error(), "PYPI_TOKEN") {
+		t.Errorf("Publish() error = %v, want both targets' credential errors", err)
+	}
+}
+
+func TestPublish_Config(t *testing.T) {
+	isolate(t)
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{"no name", Config{Targets: []string{"npm"}}, "name is required"},
+		{"no targets", Config{Name: "mytool"}, "no targets"},
+		{"no org", Config{Name: "mytool", Targets: []string{"npm"}}, "org is required"},
+		{"bad access", Config{Name: "mytool", Targets: []string{"npm"}, Npm: NpmConfig{Org: "myorg", Access: "private"}}, `invalid access "private"`},
+		{"unknown target", Config{Name: "mytool", Targets: []string{"conda"}}, "shipbin-publisher-conda"},
+		{"no artifacts", Config{Name: "mytool", Targets: []string{"pypi"}}, "no artifacts"},
+		{"negative concurrency", Config{Name: "mytool", Targets: []string{"pypi"}, Concurrency: -1}, "must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Publish(context.Background(), tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Publish() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPublish_Canceled(t *testing.T) {
+	isolate(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Publish(ctx, Config{
+		Name:          "mytool",
+		Version:       "1.2.3",
+		Artifacts:     []string{"linux/amd64:" + writeBinary(t)},
+		SkipArchCheck: true,
+		DryRun:        true,
+		Targets:       []string{"pypi"},
+	})
+	if err != context.Canceled {
+		t.Errorf("Publish() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestPack(t *testing.T) {
+	isolate(t)
+	cfg := Config{
+		Name:          "mytool",
+		Version:       "1.2.3",
+		Artifacts:     []string{"linux/amd64:" + writeBinary(t)},
+		SkipArchCheck: true,
+		Targets:       []string{"npm", "pypi"},
+		Npm:           NpmConfig{Org: "myorg"},
+	}
+
+	out := filepath.Join(t.TempDir(), "out")
+	files, err := Pack(context.Background(), cfg, out)
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+
+	want := []string{
+		"myorg-mytool-linux-x64-1.2.3.tgz",
+		"mytool-1.2.3.tgz",
+		"mytool-1.2.3-py3-none-manylinux_2_17_x86_64.manylinux2014_x86_64.whl",
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.Path)
+		data, err := os.ReadFile(filepath.Join(out, f.Path))
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Path, err)
+		}
+		if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != f.SHA256 || int64(len(data)) != f.Size {
+			t.Errorf("%s: size %d, sha256 %s don't match the file", f.Path, f.Size, f.SHA256)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(out, ManifestFile))
+	if err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	var m struct {
+		Name    string       `json:"name"`
+		Version string       `json:"version"`
+		Files   []PackedFile `json:"files"`
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	if m.Name != "mytool" || m.Version != "1.2.3" || !slices.Equal(m.Files, files) {
+		t.Errorf("manifest = %+v, want files %+v", m, files)
+	}
+}